@@ -32,10 +32,11 @@ public sealed class KafkaBroker: IKafkaBroker
         _consumer = new ConsumerBuilder<string, string>(kafkaConfiguration.BuildConsumerConfig()).Build();
     }
 
-    public async Task<DeliveryResult<string, string>> ProduceYasMessageAsync<T>(string command, T msgObject)
+    public async Task<DeliveryResult<string, string>> ProduceYasMessageAsync<T>(string command, string userKey, T msgObject)
         => await ProduceYasAsync(
             string.IsNullOrEmpty(_kafkaConfiguration.YasTopic) ? Topics.YasTopic : _kafkaConfiguration.YasTopic, 
             command, 
+            userKey,
             msgObject);
 
     public async Task<DeliveryResult<string, string>> ProduceRequestAsync<T>(string key, string activityId, T msgObject)
@@ -123,15 +124,20 @@ public sealed class KafkaBroker: IKafkaBroker
         }
     }
 
-    private async Task<DeliveryResult<string, string>> ProduceYasAsync<T>(string topic, string command, T msgObject)
+    /// <summary>
+    /// Yas commands are keyed by the user token, so all commands of the user share the partition
+    /// and are processed in order. The unique command id goes in the command-id header
+    /// </summary>
+    private async Task<DeliveryResult<string, string>> ProduceYasAsync<T>(string topic, string command, string userKey, T msgObject)
     {
         var message = new Message<string, string>
         {
             Headers = new Headers
             {
-                new Header("command", Encoding.ASCII.GetBytes(command))
+                new Header("command", Encoding.ASCII.GetBytes(command)),
+                new Header("command-id", Encoding.ASCII.GetBytes(Guid.NewGuid().ToString()))
             },
-            Key = Guid.NewGuid().ToString(),
+            Key = userKey,
             Value = JsonSerializer.Serialize(msgObject)
         };
         
@@ -27,7 +27,7 @@ public class YasManager
             token = shortid.ShortId.Generate(
                 new GenerationOptions(useNumbers: true, useSpecialCharacters: false, length: 10))
         };
-        var produce = await _kafkaBroker.ProduceYasMessageAsync("create-user", createParams);
+        var produce = await _kafkaBroker.ProduceYasMessageAsync("create-user", createParams.token, createParams);
         if (produce.Status != PersistenceStatus.Persisted)
             throw new ApplicationException("Unable to deliver create user message");
         
@@ -40,9 +40,16 @@ public class YasManager
         };
     }
     
-    public async Task AddRoute<T>(T route)
+    public async Task AddRoute(YasRoute route, string token)
     {
-        var produce = await _kafkaBroker.ProduceYasMessageAsync("add-route", route);
+        var addParams = new
+        {
+            route.UserId,
+            token,
+            route.RouteName,
+            route.Waypoints
+        };
+        var produce = await _kafkaBroker.ProduceYasMessageAsync("add-route", token, addParams);
         if (produce.Status != PersistenceStatus.Persisted)
             throw new ApplicationException("Unable to deliver add route message");
     }
@@ -54,7 +61,7 @@ public class YasManager
             routeId,
             token
         };
-        var produce = await _kafkaBroker.ProduceYasMessageAsync("delete-route", deleteParams);
+        var produce = await _kafkaBroker.ProduceYasMessageAsync("delete-route", token, deleteParams);
         if (produce.Status != PersistenceStatus.Persisted)
             throw new ApplicationException("Unable to deliver delete route message");
     }
@@ -67,7 +74,7 @@ public class YasManager
             token,
             routeName
         };
-        var produce = await _kafkaBroker.ProduceYasMessageAsync("rename-route-token", renameParams);
+        var produce = await _kafkaBroker.ProduceYasMessageAsync("rename-route-token", token, renameParams);
         if (produce.Status != PersistenceStatus.Persisted)
             throw new ApplicationException("Unable to deliver rename route message");
     }
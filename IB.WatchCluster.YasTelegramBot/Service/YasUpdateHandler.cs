@@ -189,7 +189,7 @@ public class YasUpdateHandler: IUpdateHandler
             Waypoints = points.OrderBy(wp => wp.OrderId).ToArray()
         };
         
-        await _yasManager.AddRoute(route);
+        await _yasManager.AddRoute(route, yasUser.PublicId);
         
         return CommandResult.SuccessResult(
             $" {route.RouteName} ({points.Count} way points) has been uploaded \n userId:{yasUser.PublicId}");
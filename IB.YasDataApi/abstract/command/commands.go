@@ -1,11 +1,33 @@
 package command
 
+import (
+    "time"
+)
+
 const (
     CmdCreateUser = "create-user"
     CmdAddRoute = "add-route"
     CmdDeleteRoute = "delete-route"
     CmdRenameRouteById = "rename-route-id"
     CmdRenameRouteByToken = "rename-route-token"
+    CmdAddWaypoint = "add-waypoint"
+    CmdMoveWaypoint = "move-waypoint"
+    CmdRenameWaypoint = "rename-waypoint"
+    CmdDeleteWaypoint = "delete-waypoint"
+    CmdReorderWaypoints = "reorder-waypoints"
+    CmdSimplifyRoute = "simplify-route"
+    CmdAppendTrackPoints = "append-track-points"
+)
+
+// Kafka message headers
+//
+const (
+    HeaderCommand = "command"
+    HeaderCommandId = "command-id"
+    HeaderError = "error"
+    HeaderOriginalTopic = "original-topic"
+    HeaderOriginalPartition = "original-partition"
+    HeaderOriginalOffset = "original-offset"
 )
 
 type AddUser struct {
@@ -22,12 +44,14 @@ type AddWaypoint struct {
 
 type AddRoute struct {
     UserId int64             `json:"userId"`
+    Token string             `json:"token"`
     RouteName string         `json:"routeName"`
     Waypoints []AddWaypoint  `json:"waypoints"`
 }
 
 type RenameRouteById struct {
     UserId int64    `json:"userId"`
+    Token string    `json:"token"`
     RouteId int32   `json:"routerId"`
     NewName string  `json:"newName"`
 }
@@ -43,3 +67,101 @@ type DeleteRoute struct {
     RouteId int32   `json:"routeId"`
 }
 
+// Waypoint commands address the route by the user token and the route id.
+// Position of the added waypoint is its order id, the waypoint is appended when
+// the position is missing or beyond the end of the route
+//
+type AddRouteWaypoint struct {
+    Token string         `json:"token"`
+    RouteId int32        `json:"routeId"`
+    WaypointName string  `json:"waypointName"`
+    Lat float64          `json:"lat"`
+    Lon float64          `json:"lon"`
+    Position *int32      `json:"position,omitempty"`
+}
+
+type MoveWaypoint struct {
+    Token string        `json:"token"`
+    RouteId int32       `json:"routeId"`
+    WaypointId int32    `json:"waypointId"`
+    Lat float64         `json:"lat"`
+    Lon float64         `json:"lon"`
+}
+
+type RenameWaypoint struct {
+    Token string         `json:"token"`
+    RouteId int32        `json:"routeId"`
+    WaypointId int32     `json:"waypointId"`
+    WaypointName string  `json:"waypointName"`
+}
+
+type DeleteWaypoint struct {
+    Token string        `json:"token"`
+    RouteId int32       `json:"routeId"`
+    WaypointId int32    `json:"waypointId"`
+}
+
+// Waypoint ids of the route in the new order, every waypoint of the route exactly once
+//
+type ReorderWaypoints struct {
+    Token string          `json:"token"`
+    RouteId int32         `json:"routeId"`
+    WaypointIds []int32   `json:"waypointIds"`
+}
+
+// Rewrites waypoints of the route, see navigation.ShapeOptions for the parameters
+//
+type SimplifyRoute struct {
+    Token string              `json:"token"`
+    RouteId int32             `json:"routeId"`
+    MaxPoints int             `json:"maxPoints,omitempty"`
+    ToleranceMeters float64   `json:"toleranceMeters,omitempty"`
+    DensifyNm float64         `json:"densifyNm,omitempty"`
+}
+
+// Commands are partitioned by the user key, so all commands of the same user
+// are processed in the order they were sent. The key of every command is the user token,
+// commands addressed by the user id carry the token too
+//
+func (c AddUser) UserKey() string { return c.Token }
+
+func (c AddRoute) UserKey() string { return c.Token }
+
+func (c RenameRouteById) UserKey() string { return c.Token }
+
+func (c RenameRouteByToken) UserKey() string { return c.Token }
+
+func (c DeleteRoute) UserKey() string { return c.Token }
+
+func (c AddRouteWaypoint) UserKey() string { return c.Token }
+
+func (c MoveWaypoint) UserKey() string { return c.Token }
+
+func (c RenameWaypoint) UserKey() string { return c.Token }
+
+func (c DeleteWaypoint) UserKey() string { return c.Token }
+
+func (c ReorderWaypoints) UserKey() string { return c.Token }
+
+func (c SimplifyRoute) UserKey() string { return c.Token }
+
+type TrackPoint struct {
+    Time time.Time       `json:"time"`
+    Lat float64          `json:"lat"`
+    Lon float64          `json:"lon"`
+    Sog *float64         `json:"sog,omitempty"`
+    Cog *float64         `json:"cog,omitempty"`
+    Heading *float64     `json:"heading,omitempty"`
+}
+
+// Batch of points logged by the watch in time order. The track of the user with the key
+// is created by its first batch, the name is kept when the batch has no name
+//
+type AppendTrackPoints struct {
+    Token string             `json:"token"`
+    TrackKey string          `json:"trackKey"`
+    TrackName string         `json:"trackName,omitempty"`
+    Points []TrackPoint      `json:"points"`
+}
+
+func (c AppendTrackPoints) UserKey() string { return c.Token }
@@ -0,0 +1,21 @@
+package abstract
+
+import (
+	"time"
+)
+
+// Processing states of a command sent to yas_processor
+//
+const (
+	CommandPending = "pending"
+	CommandApplied = "applied"
+	CommandFailed = "failed"
+)
+
+type CommandStatus struct {
+	CommandId     string		`json:"commandId"`
+	Command       string		`json:"command,omitempty"`
+	Status        string		`json:"status"`
+	Error         string		`json:"error,omitempty"`
+	ProcessedTime *time.Time	`json:"processedTime,omitempty"`
+}
@@ -3,6 +3,7 @@ package abstract
 import (
 	"os"
 	"strings"
+	"time"
 
 	"github.com/rs/zerolog/log"
 	"github.com/knadh/koanf"
@@ -31,6 +32,32 @@ func (listener *Listener) GetListener() string {
 	return listener.Host + ":" + listener.Port
 }
 
+// Kafka producer config. Zero values fall back to kafka-go defaults
+//
+type Producer struct {
+
+	// Acknowledgements required from the broker: none, one or all (default)
+	//
+	RequiredAcks string `koanf:"requiredAcks"`
+
+	// Maximum number of messages in a batch
+	//
+	BatchSize int `koanf:"batchSize"`
+
+	// Time limit to wait for a batch to fill up before it is sent
+	//
+	BatchTimeout time.Duration `koanf:"batchTimeout"`
+
+	// Compression codec: none (default), gzip, snappy, lz4 or zstd
+	//
+	Compression string `koanf:"compression"`
+
+	// Timeouts of write and read operations with the broker
+	//
+	WriteTimeout time.Duration `koanf:"writeTimeout"`
+	ReadTimeout time.Duration `koanf:"readTimeout"`
+}
+
 // Kafka config
 //
 type Kafka struct {
@@ -41,6 +68,77 @@ type Kafka struct {
 
 	TopicName string `koanf:"topicName"`
 
+	// Topic for domain events published after commands are applied
+	//
+	EventTopicName string `koanf:"eventTopicName"`
+
+	// Topic for command messages which can not be processed
+	//
+	DeadLetterTopic string `koanf:"deadLetterTopic"`
+
+	// Initial delay before retry of a transient failure, doubles on every attempt
+	//
+	RetryInitialInterval time.Duration `koanf:"retryInitialInterval"`
+
+	// Upper limit of the delay between retries
+	//
+	RetryMaxInterval time.Duration `koanf:"retryMaxInterval"`
+
+	// Producer settings
+	//
+	Producer Producer `koanf:"producer"`
+}
+
+// Postgres connection pool config. Zero values fall back to pgxpool defaults
+//
+type PgPool struct {
+
+	// Maximum number of connections in the pool
+	//
+	MaxConns int32 `koanf:"maxConns"`
+
+	// Minimum number of connections the pool tries to keep open
+	//
+	MinConns int32 `koanf:"minConns"`
+
+	// Idle connections are closed by the health check after this duration
+	//
+	MaxConnIdleTime time.Duration `koanf:"maxConnIdleTime"`
+
+	// Connections are closed and recreated after this duration since creation
+	//
+	MaxConnLifetime time.Duration `koanf:"maxConnLifetime"`
+
+	// Interval between health checks of idle connections
+	//
+	HealthCheckPeriod time.Duration `koanf:"healthCheckPeriod"`
+}
+
+// Retention of processed command ids unless configured
+//
+const DefaultCommandRetention = 7 * 24 * time.Hour
+
+// Command processor config
+//
+type Processor struct {
+
+	// How long ids of processed commands are kept to detect redelivered commands.
+	// REST API reports commands older than that as unknown, so both services need the same value
+	//
+	CommandRetention time.Duration `koanf:"commandRetention"`
+
+	// Interval between removals of expired command ids
+	//
+	PruneInterval time.Duration `koanf:"pruneInterval"`
+}
+
+// Returns the configured command retention or DefaultCommandRetention
+//
+func (processor Processor) CommandRetentionOrDefault() time.Duration {
+	if processor.CommandRetention > 0 {
+		return processor.CommandRetention
+	}
+	return DefaultCommandRetention
 }
 
 // configuration params
@@ -55,6 +153,10 @@ type Config struct {
 	//
 	PostgreUrl string `koanf:"pgUrl"`
 
+	// Postgres connection pool settings
+	//
+	PgPool PgPool `koanf:"pgPool"`
+
 	// Level of logging -1 Trace, 0 Info, 1 Debug, 2 Warning, 3 Error, 4 Fatal, 5 Panic
 	//
 	LogLevel int `koanf:"logLevel"`
@@ -66,21 +168,39 @@ type Config struct {
 	// Kafka config
 	//
 	Kafka Kafka `koanf:"kafka"`
+
+	// Command processor config
+	//
+	Processor Processor `koanf:"processor"`
 }
 
+// Config file used when there is no --config option
+//
+const DefaultConfigFile = "./config.yaml"
+
 // Loads config data from .yaml config file and environment variables (prefix YASR_).
 // The config file can be passed via command line --config option.
 // The default is ./config.yaml
 //
 func ConfigLoad() (Config, error) {
 
-	var config Config
 	var configFile string
 	 
 	f := flag.NewFlagSet("config", flag.ContinueOnError)
-	f.StringVar(&configFile, "config", "./config.yaml", "path to config file in yaml")
+	f.ParseErrorsWhitelist.UnknownFlags = true
+	f.StringVar(&configFile, "config", DefaultConfigFile, "path to config file in yaml")
 	f.Parse(os.Args[1:])
 
+	return ConfigLoadFile(configFile)
+}
+
+// Loads config data from the .yaml config file and environment variables (prefix YASR_),
+// for the tools which parse the command line themselves
+//
+func ConfigLoadFile(configFile string) (Config, error) {
+
+	var config Config
+
 	k := koanf.New(".")
 	if err := k.Load(file.Provider(configFile), yaml.Parser()); err != nil {
 		log.Warn().Err(err).Msg("Unable to load config file")
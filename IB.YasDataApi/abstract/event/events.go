@@ -0,0 +1,99 @@
+package event
+
+const (
+    EvtUserCreated = "user-created"
+    EvtRouteAdded = "route-added"
+    EvtRouteRenamed = "route-renamed"
+    EvtRouteDeleted = "route-deleted"
+    EvtWaypointAdded = "waypoint-added"
+    EvtWaypointMoved = "waypoint-moved"
+    EvtWaypointRenamed = "waypoint-renamed"
+    EvtWaypointDeleted = "waypoint-deleted"
+    EvtWaypointsReordered = "waypoints-reordered"
+    EvtRouteSimplified = "route-simplified"
+    EvtTrackPointsAppended = "track-points-appended"
+)
+
+// Kafka message headers, the originating command id is passed in command.HeaderCommandId
+//
+const (
+    HeaderEvent = "event"
+)
+
+type UserCreated struct {
+    TelegramId int64    `json:"telegramId"`
+    Token string        `json:"token"`
+    UserName string     `json:"userName"`
+}
+
+type RouteAdded struct {
+    UserId int64        `json:"userId"`
+    RouteId int32       `json:"routeId"`
+    RouteName string    `json:"routeName"`
+    Waypoints int       `json:"waypoints"`
+}
+
+type RouteRenamed struct {
+    UserId int64        `json:"userId,omitempty"`
+    Token string        `json:"token,omitempty"`
+    RouteId int32       `json:"routeId"`
+    RouteName string    `json:"routeName"`
+}
+
+type RouteDeleted struct {
+    Token string        `json:"token"`
+    RouteId int32       `json:"routeId"`
+}
+
+type WaypointAdded struct {
+    Token string         `json:"token"`
+    RouteId int32        `json:"routeId"`
+    WaypointId int32     `json:"waypointId"`
+    WaypointName string  `json:"waypointName"`
+    Lat float64          `json:"lat"`
+    Lon float64          `json:"lon"`
+    OrderId int32        `json:"orderId"`
+}
+
+type WaypointMoved struct {
+    Token string        `json:"token"`
+    RouteId int32       `json:"routeId"`
+    WaypointId int32    `json:"waypointId"`
+    Lat float64         `json:"lat"`
+    Lon float64         `json:"lon"`
+}
+
+type WaypointRenamed struct {
+    Token string         `json:"token"`
+    RouteId int32        `json:"routeId"`
+    WaypointId int32     `json:"waypointId"`
+    WaypointName string  `json:"waypointName"`
+}
+
+type WaypointDeleted struct {
+    Token string        `json:"token"`
+    RouteId int32       `json:"routeId"`
+    WaypointId int32    `json:"waypointId"`
+}
+
+type WaypointsReordered struct {
+    Token string          `json:"token"`
+    RouteId int32         `json:"routeId"`
+    WaypointIds []int32   `json:"waypointIds"`
+}
+
+type RouteSimplified struct {
+    Token string        `json:"token"`
+    RouteId int32       `json:"routeId"`
+    Waypoints int       `json:"waypoints"`
+}
+
+// Points are the stored points of the batch, skipped are the points not after the end of the track
+//
+type TrackPointsAppended struct {
+    Token string        `json:"token"`
+    TrackId int32       `json:"trackId"`
+    TrackKey string     `json:"trackKey"`
+    Points int          `json:"points"`
+    Skipped int         `json:"skipped"`
+}
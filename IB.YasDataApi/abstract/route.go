@@ -10,4 +10,8 @@ type Route struct {
 	RouteName  string		`json:"routeName"`
 	UploadTime time.Time	`json:"routeDate"`
 	Waypoints  []Waypoint	`json:"waypoints"`
+
+	// Set on request by navigation.AddStatistics
+	//
+	TotalDistanceNm *float64	`json:"totalDistanceNm,omitempty"`
 }
@@ -0,0 +1,45 @@
+package abstract
+
+import (
+	"time"
+)
+
+// Sort orders of the route list
+//
+const (
+	RouteSortNewest = "newest"
+	RouteSortOldest = "oldest"
+	RouteSortName = "name"
+)
+
+// Position in the route list after the last returned route. Only the key of the
+// sort order is set: the upload time for newest and oldest, the name for name
+//
+type RouteCursor struct {
+	Sort       string		`json:"s"`
+	UploadTime time.Time	`json:"t,omitempty"`
+	RouteName  string		`json:"n,omitempty"`
+	RouteId    int32		`json:"id"`
+}
+
+// Filter, order and page of the route list. Zero values do not filter:
+// no limit, no upload time bounds and no waypoint count bounds.
+// UploadedFrom is inclusive, UploadedTo is exclusive
+//
+type RouteQuery struct {
+	Limit        int32
+	Sort         string
+	After        *RouteCursor
+	NameContains string
+	UploadedFrom time.Time
+	UploadedTo   time.Time
+	MinWaypoints int32
+	MaxWaypoints int32
+}
+
+// Routes of the page and the cursor of the next page, nil on the last page
+//
+type RoutePage struct {
+	Routes []Route
+	Next   *RouteCursor
+}
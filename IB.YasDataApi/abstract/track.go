@@ -0,0 +1,48 @@
+package abstract
+
+import (
+	"time"
+)
+
+// Recorded track of the user. The track is identified by the user and the key chosen by
+// the watch, start and end are missing until the first point is stored
+//
+type Track struct {
+	TrackId    int32		`json:"trackId"`
+	UserId     int64		`json:"userId"`
+	TrackKey   string		`json:"trackKey"`
+	TrackName  string		`json:"trackName"`
+	CreateTime time.Time	`json:"createTime"`
+	StartTime  *time.Time	`json:"startTime,omitempty"`
+	EndTime    *time.Time	`json:"endTime,omitempty"`
+	PointCount int32		`json:"pointCount"`
+	Points     []TrackPoint	`json:"points,omitempty"`
+}
+
+// Logged position, SOG is in knots, COG and heading are true degrees.
+// Values the watch did not have are missing
+//
+type TrackPoint struct {
+	Time    time.Time	`json:"time"`
+	Lat     float64		`json:"lat"`
+	Lon     float64		`json:"lon"`
+	Sog     *float64	`json:"sog,omitempty"`
+	Cog     *float64	`json:"cog,omitempty"`
+	Heading *float64	`json:"heading,omitempty"`
+}
+
+// Time range and page size of the track points. Zero values do not filter:
+// no limit and no time bounds. From is inclusive, To is exclusive
+//
+type TrackPointQuery struct {
+	From  time.Time
+	To    time.Time
+	Limit int32
+}
+
+// Track with the points of the page and the time of the first point of the next page, nil on the last page
+//
+type TrackPage struct {
+	Track Track
+	Next  *time.Time
+}
@@ -6,4 +6,14 @@ type Waypoint struct {
 	Lat          float64	`json:"lat"`
 	Lon          float64	`json:"lon"`
 	OrderId      int32		`json:"orderId"`
+
+	// Leg from the previous waypoint, set on request by navigation.AddStatistics
+	//
+	LegDistanceNm *float64	`json:"legDistanceNm,omitempty"`
+	LegBearing    *float64	`json:"legBearing,omitempty"`
+
+	// Variation at the waypoint and the magnetic bearing of the leg, set on request by navigation.AddMagnetic
+	//
+	MagneticVariation  *float64	`json:"magneticVariation,omitempty"`
+	LegBearingMagnetic *float64	`json:"legBearingMagnetic,omitempty"`
 }
\ No newline at end of file
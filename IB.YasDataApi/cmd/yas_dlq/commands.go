@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"IB.YasDataApi/abstract"
+	"IB.YasDataApi/abstract/command"
+	"github.com/rs/zerolog/log"
+	"github.com/segmentio/kafka-go"
+)
+
+// Headers added by the processor when the message is moved to the dead letter topic.
+// They are removed on replay
+//
+var deadLetterHeaders = map[string]bool {
+	command.HeaderError: true,
+	command.HeaderOriginalTopic: true,
+	command.HeaderOriginalPartition: true,
+	command.HeaderOriginalOffset: true,
+}
+
+// Prints one line per message: position, time, command type, failure reason and payload
+//
+func List(ctx context.Context, config abstract.Config, filter Filter) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "PARTITION\tOFFSET\tTIME\tCOMMAND\tERROR\tPAYLOAD")
+	count := 0
+	err := ReadDeadLetters(ctx, config, filter, func(deadLetter DeadLetter) error {
+		count++
+		_, err := fmt.Fprintf(w, "%d\t%d\t%s\t%s\t%s\t%s\n",
+			deadLetter.Message.Partition,
+			deadLetter.Message.Offset,
+			deadLetter.Message.Time.Format(time.RFC3339),
+			deadLetter.Command,
+			deadLetter.Reason,
+			deadLetter.Message.Value)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	log.Info().Int("count", count).Msg("Messages listed")
+	return w.Flush()
+}
+
+// Prints all details of the message, the payload is decoded into the command structure
+//
+func Show(ctx context.Context, config abstract.Config, filter Filter) error {
+	found := false
+	err := ReadDeadLetters(ctx, config, filter, func(deadLetter DeadLetter) error {
+		found = true
+		m := deadLetter.Message
+		fmt.Printf("Partition: %d\nOffset:    %d\nTime:      %s\nKey:       %s\nCommand:   %s\nError:     %s\n",
+			m.Partition, m.Offset, m.Time.Format(time.RFC3339), m.Key, deadLetter.Command, deadLetter.Reason)
+		fmt.Println("Headers:")
+		for _, h := range m.Headers {
+			fmt.Printf("  %s: %s\n", h.Key, h.Value)
+		}
+
+		fmt.Println("Payload:")
+		payload, err := decodeCommand(deadLetter.Command, m.Value)
+		if err != nil {
+			fmt.Printf("  unable to decode payload: %s\n  %s\n", err, m.Value)
+			return nil
+		}
+		indented, err := json.MarshalIndent(payload, "  ", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Printf("  %s\n", indented)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("no message at offset %d", filter.From)
+	}
+	return nil
+}
+
+// Publishes selected messages back to the command topic with the original key and headers
+//
+func Replay(ctx context.Context, config abstract.Config, filter Filter, dryRun bool) error {
+	w := &kafka.Writer {
+		Addr: kafka.TCP(config.Kafka.Broker),
+		Topic: config.Kafka.TopicName,
+		AllowAutoTopicCreation: true,
+		Balancer: &kafka.CRC32Balancer{},
+	}
+	defer func() {
+		if err := w.Close(); err != nil {
+			log.Error().Err(err).Msg("Error close kafka connection")
+		}
+	}()
+
+	count := 0
+	err := ReadDeadLetters(ctx, config, filter, func(deadLetter DeadLetter) error {
+		m := deadLetter.Message
+		if _, err := decodeCommand(deadLetter.Command, m.Value); err != nil {
+			log.Warn().Err(err).Int("partition", m.Partition).Int64("offset", m.Offset).Msg("Skip message")
+			return nil
+		}
+
+		var headers []kafka.Header
+		for _, h := range m.Headers {
+			if !deadLetterHeaders[h.Key] {
+				headers = append(headers, h)
+			}
+		}
+
+		log.Info().
+			Int("partition", m.Partition).
+			Int64("offset", m.Offset).
+			Str("command", deadLetter.Command).
+			Bool("dryRun", dryRun).
+			Msg("Replay message")
+		count++
+		if dryRun {
+			return nil
+		}
+		return w.WriteMessages(ctx, kafka.Message { Key: m.Key, Value: m.Value, Headers: headers })
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Info().Int("count", count).Str("topic", config.Kafka.TopicName).Msg("Messages replayed")
+	return nil
+}
+
+// Parses payload into the structure of the command type
+//
+func decodeCommand(commandType string, payload []byte) (interface{}, error) {
+	var cmd interface{}
+	switch commandType {
+		case command.CmdCreateUser:
+			cmd = &command.AddUser{}
+		case command.CmdAddRoute:
+			cmd = &command.AddRoute{}
+		case command.CmdDeleteRoute:
+			cmd = &command.DeleteRoute{}
+		case command.CmdRenameRouteById:
+			cmd = &command.RenameRouteById{}
+		case command.CmdRenameRouteByToken:
+			cmd = &command.RenameRouteByToken{}
+		case command.CmdAddWaypoint:
+			cmd = &command.AddRouteWaypoint{}
+		case command.CmdMoveWaypoint:
+			cmd = &command.MoveWaypoint{}
+		case command.CmdRenameWaypoint:
+			cmd = &command.RenameWaypoint{}
+		case command.CmdDeleteWaypoint:
+			cmd = &command.DeleteWaypoint{}
+		case command.CmdReorderWaypoints:
+			cmd = &command.ReorderWaypoints{}
+		case command.CmdSimplifyRoute:
+			cmd = &command.SimplifyRoute{}
+		case command.CmdAppendTrackPoints:
+			cmd = &command.AppendTrackPoints{}
+		default:
+			return nil, fmt.Errorf("unknown command: %s", commandType)
+	}
+
+	if err := json.Unmarshal(payload, cmd); err != nil {
+		return nil, err
+	}
+	return cmd, nil
+}
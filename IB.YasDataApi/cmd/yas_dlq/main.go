@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	flag "github.com/spf13/pflag"
+
+	"IB.YasDataApi/abstract"
+)
+
+const usage = `Inspect and replay messages from the dead letter topic
+
+Usage:
+  yas_dlq list   [--partition N] [--from OFFSET] [--to OFFSET] [--command TYPE]
+  yas_dlq show   --offset OFFSET [--partition N]
+  yas_dlq replay [--partition N] [--from OFFSET] [--to OFFSET] [--command TYPE] [--dry-run]
+
+Config is loaded the same way as for yas_processor (--config file and YASR_ environment).
+Unknown flags are errors, so a mistyped filter never replays the whole topic
+
+Flags:
+`
+
+func main() {
+
+	// Setup global logger settings with defaults
+	//
+	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
+	log.Logger = zerolog.New(zerolog.ConsoleWriter{Out: os.Stderr}).With().
+		Timestamp().
+		Str("Application", "yas-dlq").
+		Logger()
+
+	// Parse command line
+	//
+	var filter Filter
+	var offset int64
+	var dryRun bool
+	var configFile string
+
+	f := flag.NewFlagSet("yas_dlq", flag.ContinueOnError)
+	f.StringVar(&configFile, "config", abstract.DefaultConfigFile, "path to config file in yaml")
+	f.IntVar(&filter.Partition, "partition", -1, "partition of the dead letter topic, -1 for all")
+	f.Int64Var(&filter.From, "from", 0, "first offset to process")
+	f.Int64Var(&filter.To, "to", math.MaxInt64, "last offset to process")
+	f.StringVar(&filter.Command, "command", "", "process only messages of the command type")
+	f.Int64Var(&offset, "offset", -1, "offset of the message to show")
+	f.BoolVar(&dryRun, "dry-run", false, "list messages to replay without publishing")
+	f.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		f.PrintDefaults()
+	}
+	if err := f.Parse(os.Args[1:]); err != nil {
+		if err != flag.ErrHelp {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		os.Exit(2)
+	}
+	if f.NArg() != 1 {
+		f.Usage()
+		os.Exit(2)
+	}
+
+	// Load config
+	//
+	config, err := abstract.ConfigLoadFile(configFile)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Fatal: unable to load config")
+	}
+	zerolog.SetGlobalLevel(zerolog.Level(config.LogLevel))
+
+	if config.Kafka.DeadLetterTopic == "" {
+		log.Fatal().Msg("Fatal: dead letter topic is not configured")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	switch f.Arg(0) {
+		case "list":
+			err = List(ctx, config, filter)
+
+		case "show":
+			if offset < 0 {
+				log.Fatal().Msg("Fatal: --offset is required")
+			}
+			filter.From, filter.To, filter.Command = offset, offset, ""
+			err = Show(ctx, config, filter)
+
+		case "replay":
+			err = Replay(ctx, config, filter, dryRun)
+
+		default:
+			f.Usage()
+			os.Exit(2)
+	}
+
+	if err != nil {
+		log.Fatal().Err(err).Msgf("Fatal: unable to %s messages", f.Arg(0))
+	}
+}
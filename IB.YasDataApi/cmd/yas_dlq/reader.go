@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"sort"
+
+	"IB.YasDataApi/abstract"
+	"IB.YasDataApi/abstract/command"
+	"github.com/rs/zerolog/log"
+	"github.com/segmentio/kafka-go"
+)
+
+// Selects messages in the dead letter topic
+//
+type Filter struct {
+
+	// Partition to read, -1 for all partitions
+	//
+	Partition int
+
+	// Offset range, both ends are inclusive
+	//
+	From int64
+	To int64
+
+	// Command type, empty for all commands
+	//
+	Command string
+}
+
+// Dead letter message with the decoded headers
+//
+type DeadLetter struct {
+	Message kafka.Message
+	Command string
+	Reason string
+}
+
+func newDeadLetter(message kafka.Message) DeadLetter {
+	deadLetter := DeadLetter{ Message: message, Command: "unknown" }
+	for _, h := range message.Headers {
+		switch h.Key {
+			case command.HeaderCommand:
+				deadLetter.Command = string(h.Value)
+			case command.HeaderError:
+				deadLetter.Reason = string(h.Value)
+		}
+	}
+	return deadLetter
+}
+
+// Reads messages from the dead letter topic matching the filter, from the first
+// to the last available offset, without committing any consumer offsets
+//
+func ReadDeadLetters(ctx context.Context, config abstract.Config, filter Filter, handle func(DeadLetter) error) error {
+	conn, err := kafka.DialContext(ctx, "tcp", config.Kafka.Broker)
+	if err != nil {
+		return err
+	}
+	partitions, err := conn.ReadPartitions(config.Kafka.DeadLetterTopic)
+	conn.Close()
+	if err != nil {
+		return err
+	}
+	sort.Slice(partitions, func(i, j int) bool { return partitions[i].ID < partitions[j].ID })
+
+	for _, p := range partitions {
+		if filter.Partition >= 0 && filter.Partition != p.ID {
+			continue
+		}
+		if err := readPartition(ctx, config, p.ID, filter, handle); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readPartition(ctx context.Context, config abstract.Config, partition int, filter Filter, handle func(DeadLetter) error) error {
+	leader, err := kafka.DialLeader(ctx, "tcp", config.Kafka.Broker, config.Kafka.DeadLetterTopic, partition)
+	if err != nil {
+		return err
+	}
+	first, last, err := leader.ReadOffsets()
+	leader.Close()
+	if err != nil {
+		return err
+	}
+
+	if filter.From > first {
+		first = filter.From
+	}
+	if filter.To < last - 1 {
+		last = filter.To + 1
+	}
+	if first >= last {
+		return nil
+	}
+	log.Debug().Int("partition", partition).Int64("first", first).Int64("last", last).Msg("Read partition")
+
+	r := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:   []string{ config.Kafka.Broker },
+		Topic:     config.Kafka.DeadLetterTopic,
+		Partition: partition,
+	})
+	defer r.Close()
+	if err := r.SetOffset(first); err != nil {
+		return err
+	}
+
+	for {
+		m, err := r.FetchMessage(ctx)
+		if err != nil {
+			return err
+		}
+		deadLetter := newDeadLetter(m)
+		if filter.Command == "" || filter.Command == deadLetter.Command {
+			if err := handle(deadLetter); err != nil {
+				return err
+			}
+		}
+		if m.Offset >= last - 1 {
+			return nil
+		}
+	}
+}
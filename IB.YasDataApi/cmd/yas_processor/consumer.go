@@ -3,28 +3,129 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"strconv"
+	"time"
 
 	"IB.YasDataApi/abstract"
 	"IB.YasDataApi/abstract/command"
+	"IB.YasDataApi/abstract/event"
 	"IB.YasDataApi/dal"
+	"IB.YasDataApi/navigation"
+	"IB.YasDataApi/routeformat"
+	"IB.YasDataApi/telemetry"
+	"github.com/cenkalti/backoff/v4"
 	"github.com/rs/zerolog/log"
 	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/ksuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
-func Subscribe(config abstract.Config, dal dal.Dal) {
+var tracer = otel.Tracer("IB.YasDataApi/processor")
+
+// Dependencies shared by partition workers
+//
+type Consumer struct {
+	config abstract.Config
+	dal dal.Dal
+	deadLetter *DeadLetter
+	events *EventPublisher
+}
+
+// Joins the consumer group and processes assigned partitions until the context is cancelled.
+// Every partition is consumed by its own worker, so commands of the same user stay ordered
+// while different partitions are processed concurrently
+//
+func Subscribe(ctx context.Context, config abstract.Config, dal dal.Dal) {
+	group, err := kafka.NewConsumerGroup(kafka.ConsumerGroupConfig{
+		ID:      "yas-proc-consumer",
+		Brokers: []string{ config.Kafka.Broker },
+		Topics:  []string{ config.Kafka.TopicName },
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("Unable to create consumer group")
+		return
+	}
+	defer func() {
+		if err := group.Close(); err != nil {
+			log.Error().Err(err).Msg("Error close consumer group")
+		}
+	}()
+
+	consumer := &Consumer{
+		config: config,
+		dal: dal,
+		deadLetter: NewDeadLetter(config),
+		events: NewEventPublisher(config),
+	}
+	defer consumer.deadLetter.Close()
+	defer consumer.events.Close()
+
+	for {
+		gen, err := group.Next(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				log.Info().Msg("Stop consuming messages")
+				return
+			}
+			log.Error().Err(err).Msg("Error join consumer group")
+			continue
+		}
+
+		for _, assignment := range gen.Assignments[config.Kafka.TopicName] {
+			partition, offset := assignment.ID, assignment.Offset
+			log.Info().Int("partition", partition).Int64("offset", offset).Int32("generation", gen.ID).Msg("Partition assigned")
+			gen.Start(func(ctx context.Context) {
+				consumer.consumePartition(ctx, gen, partition, offset)
+			})
+		}
+	}
+}
+
+// Reads messages of one partition and commits the offset only after the message
+// has been processed or moved to the dead letter topic, so messages are delivered
+// at least once. Fetch errors are retried with exponential backoff. Returns when the generation ends
+//
+func (consumer *Consumer) consumePartition(ctx context.Context, gen *kafka.Generation, partition int, offset int64) {
 	r := kafka.NewReader(kafka.ReaderConfig{
-		Brokers:   []string{ config.Kafka.Broker },
-		GroupID:   "yas-proc-consumer",
-		Topic:     config.Kafka.TopicName,
+		Brokers:   []string{ consumer.config.Kafka.Broker },
+		Topic:     consumer.config.Kafka.TopicName,
+		Partition: partition,
 	})
+	defer func() {
+		if err := r.Close(); err != nil {
+			log.Error().Err(err).Msg("Error close kafka reader")
+		}
+	}()
 
+	if err := r.SetOffset(offset); err != nil {
+		log.Error().Err(err).Int("partition", partition).Msg("Unable to set offset")
+		return
+	}
+
+	fetchBackOff := consumer.backOff()
 	for {
-		m, err := r.ReadMessage(context.Background())
+		m, err := r.FetchMessage(ctx)
 		if err != nil {
-			log.Error().Err(err).Msg("Error read message")
+			if ctx.Err() != nil {
+				log.Info().Int("partition", partition).Msg("Stop consuming partition")
+				return
+			}
+			next := fetchBackOff.NextBackOff()
+			log.Error().Err(err).Int("partition", partition).Dur("retryIn", next).Msg("Error fetch message")
+			select {
+				case <-ctx.Done():
+					log.Info().Int("partition", partition).Msg("Stop consuming partition")
+					return
+				case <-time.After(next):
+			}
 			continue
 		}
+		fetchBackOff.Reset()
 		log.Debug().
 			Str("Topic", m.Topic).
 			Str("Partition", strconv.Itoa(m.Partition)).
@@ -33,20 +134,125 @@ func Subscribe(config abstract.Config, dal dal.Dal) {
 			Str("Value", string(m.Value)).
 			Interface("Headers", m.Headers).
 			Msg("got message")
-		
-		dispatcher(dal, m)
+
+		if err := consumer.process(ctx, m); err != nil {
+			log.Info().Err(err).Int("partition", partition).Msg("Stop consuming partition, offset is not committed")
+			return
+		}
+
+		err = gen.CommitOffsets(map[string]map[int]int64{ m.Topic: { m.Partition: m.Offset + 1 } })
+		if err != nil {
+			log.Error().Err(err).Int("partition", partition).Msg("Error commit offset")
+		}
 	}
 }
 
-func dispatcher(dal dal.Dal, message kafka.Message) {
-	cmd := "unknown"
-	for _, v := range message.Headers {
-		if v.Key == "command" {
-			cmd = string(v.Value)
-		}
+// Dispatches message retrying transient failures with exponential backoff and publishes
+// the resulting domain event. The event is stored with the command, so a command redelivered
+// after a crash between the database commit and the publishing publishes the stored event again:
+// events are delivered at least once, consumers dedupe them by the command id header.
+// Messages failed permanently are moved to the dead letter topic and recorded as failed.
+// The processing span continues the trace started by the REST request.
+// Returns error only if the processing was interrupted by context
+//
+func (consumer *Consumer) process(ctx context.Context, message kafka.Message) error {
+	cmd, commandId := commandHeaders(message)
+
+	ctx, span := tracer.Start(telemetry.ExtractMessage(ctx, message), "process " + cmd,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "kafka"),
+			attribute.String("messaging.destination", message.Topic),
+			attribute.Int("messaging.kafka.partition", message.Partition),
+			attribute.Int64("messaging.kafka.offset", message.Offset),
+			attribute.String("command", cmd),
+			attribute.String("command.id", commandId)))
+	defer span.End()
+
+	var evt Event
+	err := consumer.retry(ctx, "process message", func() error {
+		var err error
+		evt, err = dispatcher(ctx, consumer.dal, message)
+		return err
+	})
+	if errors.Is(err, dal.ErrDuplicateCommand) {
+		log.Info().Str("commandId", commandId).Msg("Duplicate command, publish the stored event")
+		err = consumer.retry(ctx, "load command event", func() error {
+			var err error
+			evt, err = consumer.dal.QueryCommandEvent(ctx, commandId)
+			return dalError(err)
+		})
+	}
+	if err == nil {
+		return consumer.retry(ctx, "publish event", func() error {
+			return consumer.events.Publish(ctx, commandId, message, evt)
+		})
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	log.Error().Err(err).Int64("offset", message.Offset).Msg("Unable to process message, move to dead letter topic")
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+	reason := err
+	err = consumer.retry(ctx, "send message to dead letter topic", func() error {
+		return consumer.deadLetter.Send(ctx, message, reason)
+	})
+	if err != nil {
+		return err
+	}
+
+	if commandId == "" {
+		return nil
+	}
+	err = consumer.retry(ctx, "record failed command", func() error {
+		return dalError(consumer.dal.ExecCommandFailed(ctx, commandId, cmd, reason.Error()))
+	})
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err != nil {
+		log.Error().Err(err).Str("commandId", commandId).Msg("Unable to record failed command")
+	}
+	return nil
+}
+
+// Runs operation until it succeeds, fails permanently or the context is cancelled.
+// Delay between attempts grows exponentially without time limit
+//
+func (consumer *Consumer) retry(ctx context.Context, operation string, fn backoff.Operation) error {
+	return backoff.RetryNotify(
+		fn,
+		backoff.WithContext(consumer.backOff(), ctx),
+		func(err error, next time.Duration) {
+			log.Warn().Err(err).Dur("retryIn", next).Msgf("Unable to %s, retry", operation)
+		})
+}
+
+// Returns exponential backoff policy of the config without time limit
+//
+func (consumer *Consumer) backOff() *backoff.ExponentialBackOff {
+	policy := backoff.NewExponentialBackOff()
+	policy.MaxElapsedTime = 0
+	if consumer.config.Kafka.RetryInitialInterval > 0 {
+		policy.InitialInterval = consumer.config.Kafka.RetryInitialInterval
 	}
-	if cmd == "unknown" {
-		log.Warn().Msg("Unknown message")
+	if consumer.config.Kafka.RetryMaxInterval > 0 {
+		policy.MaxInterval = consumer.config.Kafka.RetryMaxInterval
+	}
+	return policy
+}
+
+// Executes command from the message and returns the domain event of the change, the event is
+// stored with the command id in the command transaction. Duplicate commands return permanent
+// ErrDuplicateCommand. Messages without the command id and errors
+// which will not go away on retry are wrapped as backoff.Permanent
+//
+func dispatcher(ctx context.Context, dal dal.Dal, message kafka.Message) (Event, error) {
+	cmd, commandId := commandHeaders(message)
+	if commandId == "" {
+		return Event{}, backoff.Permanent(fmt.Errorf("%s message has no command id", cmd))
 	}
 
 	switch cmd {
@@ -54,51 +260,328 @@ func dispatcher(dal dal.Dal, message kafka.Message) {
 			var addUserCommand command.AddUser
 			err := json.Unmarshal(message.Value, &addUserCommand)
 			if err != nil {
-				log.Error().Err(err).Msg("Unable to parse add user message")
-				break
+				return Event{}, backoff.Permanent(fmt.Errorf("unable to parse add user message: %w", err))
+			}
+			evt := Event{
+				Type: event.EvtUserCreated,
+				Payload: event.UserCreated {
+					TelegramId: addUserCommand.TelegramId,
+					Token: addUserCommand.Token,
+					UserName: addUserCommand.UserName,
+				},
 			}
-			dal.ExecAddUser(addUserCommand)
+			if err := dal.ExecAddUser(ctx, commandId, addUserCommand, evt); err != nil {
+				return Event{}, dalError(err)
+			}
+			return evt, nil
 
 		case command.CmdAddRoute:
 			var addRouteCommand command.AddRoute
 			err := json.Unmarshal(message.Value, &addRouteCommand)
 			if err != nil {
-				log.Error().Err(err).Msg("Unable to parse add route message")
-				break
+				return Event{}, backoff.Permanent(fmt.Errorf("unable to parse add route message: %w", err))
+			}
+			routeAdded := func(routeId int32) Event {
+				return Event{
+					Type: event.EvtRouteAdded,
+					Payload: event.RouteAdded {
+						UserId: addRouteCommand.UserId,
+						RouteId: routeId,
+						RouteName: addRouteCommand.RouteName,
+						Waypoints: len(addRouteCommand.Waypoints),
+					},
+				}
 			}
-			routeId, _ := dal.ExecAddRoute(addRouteCommand)
-			for id, wp := range addRouteCommand.Waypoints {
-				dal.ExecAddWaypoint(routeId, int32(id), wp)
+			routeId, err := dal.ExecAddRoute(ctx, commandId, addRouteCommand, routeAdded)
+			if err != nil {
+				return Event{}, dalError(err)
 			}
+			log.Info().Int32("routeId", routeId).Int("waypoints", len(addRouteCommand.Waypoints)).Msg("Route added")
+			return routeAdded(routeId), nil
 
 		case command.CmdDeleteRoute:
 			var deleteRouteCommand command.DeleteRoute
 			err := json.Unmarshal(message.Value, &deleteRouteCommand)
 			if err != nil {
-				log.Error().Err(err).Msg("Unable to parse delete route message")
-				break
+				return Event{}, backoff.Permanent(fmt.Errorf("unable to parse delete route message: %w", err))
+			}
+			evt := Event{
+				Type: event.EvtRouteDeleted,
+				Payload: event.RouteDeleted {
+					Token: deleteRouteCommand.Token,
+					RouteId: deleteRouteCommand.RouteId,
+				},
 			}
-			dal.ExecDeleteRoute(deleteRouteCommand)
+			if err := dal.ExecDeleteRoute(ctx, commandId, deleteRouteCommand, evt); err != nil {
+				return Event{}, dalError(err)
+			}
+			return evt, nil
 
 		case command.CmdRenameRouteById:
 			var renameRouteCommand command.RenameRouteById
 			err := json.Unmarshal(message.Value, &renameRouteCommand)
 			if err != nil {
-				log.Error().Err(err).Msg("Unable to parse rename route message")
-				break
+				return Event{}, backoff.Permanent(fmt.Errorf("unable to parse rename route message: %w", err))
+			}
+			evt := Event{
+				Type: event.EvtRouteRenamed,
+				Payload: event.RouteRenamed {
+					UserId: renameRouteCommand.UserId,
+					RouteId: renameRouteCommand.RouteId,
+					RouteName: renameRouteCommand.NewName,
+				},
+			}
+			err = dal.ExecRenameRouteById(ctx, commandId, renameRouteCommand.RouteId, renameRouteCommand.UserId, renameRouteCommand.NewName, evt)
+			if err != nil {
+				return Event{}, dalError(err)
 			}
-			dal.ExecRenameRouteById(renameRouteCommand.RouteId, renameRouteCommand.UserId, renameRouteCommand.NewName)
+			return evt, nil
 
 		case command.CmdRenameRouteByToken:
 			var renameRouteCommand command.RenameRouteByToken
 			err := json.Unmarshal(message.Value, &renameRouteCommand)
 			if err != nil {
-				log.Error().Err(err).Msg("Unable to parse rename route message")
-				break
+				return Event{}, backoff.Permanent(fmt.Errorf("unable to parse rename route message: %w", err))
+			}
+			evt := Event{
+				Type: event.EvtRouteRenamed,
+				Payload: event.RouteRenamed {
+					Token: renameRouteCommand.Token,
+					RouteId: renameRouteCommand.RouteId,
+					RouteName: renameRouteCommand.RouteName,
+				},
+			}
+			if err := dal.ExecRenameRouteByToken(ctx, commandId, renameRouteCommand, evt); err != nil {
+				return Event{}, dalError(err)
+			}
+			return evt, nil
+
+		case command.CmdAddWaypoint:
+			var addWaypointCommand command.AddRouteWaypoint
+			err := json.Unmarshal(message.Value, &addWaypointCommand)
+			if err != nil {
+				return Event{}, backoff.Permanent(fmt.Errorf("unable to parse add waypoint message: %w", err))
+			}
+			waypointAdded := func(waypoint abstract.Waypoint) Event {
+				return Event{
+					Type: event.EvtWaypointAdded,
+					Payload: event.WaypointAdded {
+						Token: addWaypointCommand.Token,
+						RouteId: addWaypointCommand.RouteId,
+						WaypointId: waypoint.WaypointId,
+						WaypointName: waypoint.WaypointName,
+						Lat: waypoint.Lat,
+						Lon: waypoint.Lon,
+						OrderId: waypoint.OrderId,
+					},
+				}
+			}
+			waypoint, err := dal.ExecAddWaypoint(ctx, commandId, addWaypointCommand, waypointAdded)
+			if err != nil {
+				return Event{}, dalError(err)
+			}
+			return waypointAdded(waypoint), nil
+
+		case command.CmdMoveWaypoint:
+			var moveWaypointCommand command.MoveWaypoint
+			err := json.Unmarshal(message.Value, &moveWaypointCommand)
+			if err != nil {
+				return Event{}, backoff.Permanent(fmt.Errorf("unable to parse move waypoint message: %w", err))
 			}
-			dal.ExecRenameRouteByToken(renameRouteCommand)
+			evt := Event{
+				Type: event.EvtWaypointMoved,
+				Payload: event.WaypointMoved {
+					Token: moveWaypointCommand.Token,
+					RouteId: moveWaypointCommand.RouteId,
+					WaypointId: moveWaypointCommand.WaypointId,
+					Lat: moveWaypointCommand.Lat,
+					Lon: moveWaypointCommand.Lon,
+				},
+			}
+			if err := dal.ExecMoveWaypoint(ctx, commandId, moveWaypointCommand, evt); err != nil {
+				return Event{}, dalError(err)
+			}
+			return evt, nil
+
+		case command.CmdRenameWaypoint:
+			var renameWaypointCommand command.RenameWaypoint
+			err := json.Unmarshal(message.Value, &renameWaypointCommand)
+			if err != nil {
+				return Event{}, backoff.Permanent(fmt.Errorf("unable to parse rename waypoint message: %w", err))
+			}
+			evt := Event{
+				Type: event.EvtWaypointRenamed,
+				Payload: event.WaypointRenamed {
+					Token: renameWaypointCommand.Token,
+					RouteId: renameWaypointCommand.RouteId,
+					WaypointId: renameWaypointCommand.WaypointId,
+					WaypointName: renameWaypointCommand.WaypointName,
+				},
+			}
+			if err := dal.ExecRenameWaypoint(ctx, commandId, renameWaypointCommand, evt); err != nil {
+				return Event{}, dalError(err)
+			}
+			return evt, nil
+
+		case command.CmdDeleteWaypoint:
+			var deleteWaypointCommand command.DeleteWaypoint
+			err := json.Unmarshal(message.Value, &deleteWaypointCommand)
+			if err != nil {
+				return Event{}, backoff.Permanent(fmt.Errorf("unable to parse delete waypoint message: %w", err))
+			}
+			evt := Event{
+				Type: event.EvtWaypointDeleted,
+				Payload: event.WaypointDeleted {
+					Token: deleteWaypointCommand.Token,
+					RouteId: deleteWaypointCommand.RouteId,
+					WaypointId: deleteWaypointCommand.WaypointId,
+				},
+			}
+			if err := dal.ExecDeleteWaypoint(ctx, commandId, deleteWaypointCommand, evt); err != nil {
+				return Event{}, dalError(err)
+			}
+			return evt, nil
+
+		case command.CmdReorderWaypoints:
+			var reorderWaypointsCommand command.ReorderWaypoints
+			err := json.Unmarshal(message.Value, &reorderWaypointsCommand)
+			if err != nil {
+				return Event{}, backoff.Permanent(fmt.Errorf("unable to parse reorder waypoints message: %w", err))
+			}
+			evt := Event{
+				Type: event.EvtWaypointsReordered,
+				Payload: event.WaypointsReordered {
+					Token: reorderWaypointsCommand.Token,
+					RouteId: reorderWaypointsCommand.RouteId,
+					WaypointIds: reorderWaypointsCommand.WaypointIds,
+				},
+			}
+			if err := dal.ExecReorderWaypoints(ctx, commandId, reorderWaypointsCommand, evt); err != nil {
+				return Event{}, dalError(err)
+			}
+			return evt, nil
+
+		case command.CmdSimplifyRoute:
+			var simplifyRouteCommand command.SimplifyRoute
+			err := json.Unmarshal(message.Value, &simplifyRouteCommand)
+			if err != nil {
+				return Event{}, backoff.Permanent(fmt.Errorf("unable to parse simplify route message: %w", err))
+			}
+			options := navigation.ShapeOptions {
+				MaxPoints: simplifyRouteCommand.MaxPoints,
+				ToleranceMeters: simplifyRouteCommand.ToleranceMeters,
+				DensifyNm: simplifyRouteCommand.DensifyNm,
+			}
+			routeSimplified := func(waypoints int) Event {
+				return Event{
+					Type: event.EvtRouteSimplified,
+					Payload: event.RouteSimplified {
+						Token: simplifyRouteCommand.Token,
+						RouteId: simplifyRouteCommand.RouteId,
+						Waypoints: waypoints,
+					},
+				}
+			}
+			waypoints, err := dal.ExecSimplifyRoute(
+				ctx,
+				commandId,
+				simplifyRouteCommand.Token,
+				simplifyRouteCommand.RouteId,
+				func(waypoints []abstract.Waypoint) ([]abstract.Waypoint, error) {
+					return navigation.Reshape(waypoints, options, routeformat.MaxWaypoints)
+				},
+				routeSimplified)
+			if err != nil {
+				return Event{}, dalError(err)
+			}
+			log.Info().Int32("routeId", simplifyRouteCommand.RouteId).Int("waypoints", waypoints).Msg("Route simplified")
+			return routeSimplified(waypoints), nil
+
+		case command.CmdAppendTrackPoints:
+			var appendPointsCommand command.AppendTrackPoints
+			err := json.Unmarshal(message.Value, &appendPointsCommand)
+			if err != nil {
+				return Event{}, backoff.Permanent(fmt.Errorf("unable to parse append track points message: %w", err))
+			}
+			pointsAppended := func(trackId int32, points int) Event {
+				return Event{
+					Type: event.EvtTrackPointsAppended,
+					Payload: event.TrackPointsAppended {
+						Token: appendPointsCommand.Token,
+						TrackId: trackId,
+						TrackKey: appendPointsCommand.TrackKey,
+						Points: points,
+						Skipped: len(appendPointsCommand.Points) - points,
+					},
+				}
+			}
+			trackId, points, err := dal.ExecAppendTrackPoints(ctx, commandId, appendPointsCommand, pointsAppended)
+			if err != nil {
+				return Event{}, dalError(err)
+			}
+			log.Info().Int32("trackId", trackId).Int("points", points).Msg("Track points appended")
+			return pointsAppended(trackId, points), nil
 
 		default:
-			log.Warn().Str("command", cmd).Msg("Unknown command")
+			return Event{}, backoff.Permanent(fmt.Errorf("unknown command: %s", cmd))
+	}
+}
+
+// Returns command type and command id of the message.
+// Messages produced before the user key partitioning carry the command id as a key, the key
+// is taken only if it is a ksuid or a GUID: now the key is the user token, which must never be
+// recorded as the command id. Returns empty command id if the message has none
+//
+func commandHeaders(message kafka.Message) (string, string) {
+	cmd := "unknown"
+	commandId := ""
+	for _, v := range message.Headers {
+		switch v.Key {
+			case command.HeaderCommand:
+				cmd = string(v.Value)
+			case command.HeaderCommandId:
+				commandId = string(v.Value)
+		}
+	}
+	if commandId == "" && isLegacyCommandId(string(message.Key)) {
+		commandId = string(message.Key)
+	}
+	return cmd, commandId
+}
+
+// Command ids of the REST API are ksuids, the telegram bot used GUIDs
+//
+func isLegacyCommandId(key string) bool {
+	if _, err := ksuid.Parse(key); err == nil {
+		return true
 	}
-}
\ No newline at end of file
+	return isGuid(key)
+}
+
+// Checks GUID in the default xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx format
+//
+func isGuid(value string) bool {
+	if len(value) != 36 {
+		return false
+	}
+	for i, c := range value {
+		switch {
+			case i == 8 || i == 13 || i == 18 || i == 23:
+				if c != '-' {
+					return false
+				}
+			case !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'):
+				return false
+		}
+	}
+	return true
+}
+
+// Marks database errors which are not transient, duplicate commands among them, as permanent
+//
+func dalError(err error) error {
+	if err != nil && !dal.IsTransientError(err) {
+		return backoff.Permanent(err)
+	}
+	return err
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"strconv"
+
+	"IB.YasDataApi/abstract"
+	"IB.YasDataApi/abstract/command"
+	"github.com/rs/zerolog/log"
+	"github.com/segmentio/kafka-go"
+)
+
+// Publishes messages which can not be processed to the dead letter topic
+//
+type DeadLetter struct {
+	writer *kafka.Writer
+}
+
+func NewDeadLetter(config abstract.Config) *DeadLetter {
+	if config.Kafka.DeadLetterTopic == "" {
+		log.Warn().Msg("Dead letter topic is not configured, failed messages will be dropped")
+		return &DeadLetter{}
+	}
+
+	return &DeadLetter{
+		writer: &kafka.Writer {
+			Addr: kafka.TCP(config.Kafka.Broker),
+			Topic: config.Kafka.DeadLetterTopic,
+			AllowAutoTopicCreation: true,
+		},
+	}
+}
+
+// Sends copy of the message with original headers, the failure reason
+// and the original position in the command topic
+//
+func (deadLetter *DeadLetter) Send(ctx context.Context, message kafka.Message, reason error) error {
+	if deadLetter.writer == nil {
+		log.Error().Err(reason).Str("Value", string(message.Value)).Msg("Message dropped")
+		return nil
+	}
+
+	headers := make([]kafka.Header, 0, len(message.Headers) + 4)
+	headers = append(headers, message.Headers...)
+	headers = append(headers,
+		kafka.Header{ Key: command.HeaderError, Value: []byte(reason.Error()) },
+		kafka.Header{ Key: command.HeaderOriginalTopic, Value: []byte(message.Topic) },
+		kafka.Header{ Key: command.HeaderOriginalPartition, Value: []byte(strconv.Itoa(message.Partition)) },
+		kafka.Header{ Key: command.HeaderOriginalOffset, Value: []byte(strconv.FormatInt(message.Offset, 10)) },
+	)
+
+	return deadLetter.writer.WriteMessages(ctx, kafka.Message {
+		Key: message.Key,
+		Value: message.Value,
+		Headers: headers,
+	})
+}
+
+func (deadLetter *DeadLetter) Close() {
+	if deadLetter.writer == nil {
+		return
+	}
+	if err := deadLetter.writer.Close(); err != nil {
+		log.Error().Err(err).Msg("Error close dead letter writer")
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+
+	"IB.YasDataApi/abstract"
+	"IB.YasDataApi/abstract/command"
+	"IB.YasDataApi/abstract/event"
+	"IB.YasDataApi/dal"
+	"IB.YasDataApi/telemetry"
+	"github.com/rs/zerolog/log"
+	"github.com/segmentio/kafka-go"
+)
+
+// Domain event produced by an applied command
+//
+type Event = dal.CommandEvent
+
+// Publishes domain events to the events topic
+//
+type EventPublisher struct {
+	writer *kafka.Writer
+}
+
+func NewEventPublisher(config abstract.Config) *EventPublisher {
+	if config.Kafka.EventTopicName == "" {
+		log.Warn().Msg("Event topic is not configured, domain events will not be published")
+		return &EventPublisher{}
+	}
+
+	return &EventPublisher{
+		writer: &kafka.Writer {
+			Addr: kafka.TCP(config.Kafka.Broker),
+			Topic: config.Kafka.EventTopicName,
+			AllowAutoTopicCreation: true,
+			Balancer: &kafka.CRC32Balancer{},
+			RequiredAcks: kafka.RequireAll,
+		},
+	}
+}
+
+// Publishes event with the user key of the originating command, so events
+// of the same user keep the order of commands. The event carries the trace context of ctx
+//
+func (publisher *EventPublisher) Publish(ctx context.Context, commandId string, message kafka.Message, evt Event) error {
+	if publisher.writer == nil || evt.Type == "" {
+		return nil
+	}
+
+	payload, err := json.Marshal(evt.Payload)
+	if err != nil {
+		return err
+	}
+
+	eventMessage := kafka.Message {
+		Key: message.Key,
+		Value: payload,
+		Headers: []kafka.Header {
+			{ Key: event.HeaderEvent, Value: []byte(evt.Type) },
+			{ Key: command.HeaderCommandId, Value: []byte(commandId) },
+		},
+	}
+	telemetry.InjectMessage(ctx, &eventMessage)
+
+	return publisher.writer.WriteMessages(ctx, eventMessage)
+}
+
+func (publisher *EventPublisher) Close() {
+	if publisher.writer == nil {
+		return
+	}
+	if err := publisher.writer.Close(); err != nil {
+		log.Error().Err(err).Msg("Error close event writer")
+	}
+}
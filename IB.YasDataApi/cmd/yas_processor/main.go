@@ -1,13 +1,17 @@
 package main
 
 import (
+	"context"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 
 	"IB.YasDataApi/abstract"
 	"IB.YasDataApi/dal"
+	"IB.YasDataApi/telemetry"
 )
 
 func main() {
@@ -36,7 +40,27 @@ func main() {
 	
 	// Setup data access layer
 	//
-	dataLayer := dal.New(config)
+	dataLayer, err := dal.New(config)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Fatal: unable to setup database pool")
+	}
+	defer dataLayer.Close()
+
+	// Setup telemetry, traces continue the ones started by the REST requests
+	//
+	tel, _ := telemetry.Setup(config, "IB.YasDataApi/processor")
+	defer tel.Shutdown()
+
+	if err := tel.RegisterDbPoolMetrics(dataLayer.Pool); err != nil {
+		log.Error().Err(err).Msg("Unable to register database pool metrics")
+	}
+
+	// Consume messages until termination signal
+	//
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	go PruneProcessedCommands(ctx, config, dataLayer)
 
-	Subscribe(config, dataLayer)
+	Subscribe(ctx, config, dataLayer)
 }
\ No newline at end of file
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"IB.YasDataApi/abstract"
+	"IB.YasDataApi/dal"
+	"github.com/rs/zerolog/log"
+)
+
+const defaultPruneInterval = time.Hour
+
+// Periodically removes ids of processed commands older than the retention period
+// until the context is cancelled
+//
+func PruneProcessedCommands(ctx context.Context, config abstract.Config, dal dal.Dal) {
+	retention := config.Processor.CommandRetentionOrDefault()
+	interval := config.Processor.PruneInterval
+	if interval <= 0 {
+		interval = defaultPruneInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		removed, err := dal.PruneProcessedCommands(ctx, time.Now().Add(-retention))
+		if err != nil {
+			log.Error().Err(err).Msg("Unable to prune processed commands")
+		} else {
+			log.Debug().Int64("removed", removed).Msg("Processed commands pruned")
+		}
+
+		select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+		}
+	}
+}
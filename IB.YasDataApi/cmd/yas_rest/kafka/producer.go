@@ -6,44 +6,112 @@ import (
 
 	"IB.YasDataApi/abstract"
 	"IB.YasDataApi/abstract/command"
+	"IB.YasDataApi/telemetry"
 	"github.com/rs/zerolog/log"
 	"github.com/segmentio/kafka-go"
 	"github.com/segmentio/ksuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var tracer = otel.Tracer("IB.YasDataApi/kafka")
+
 type ICommand interface {
-	command.AddRoute | command.AddUser | command.AddWaypoint | command.RenameRouteById | command.RenameRouteByToken | command.DeleteRoute
-} 
-
-func SendCommand[T ICommand](config abstract.Config, commandType string, command T) {
-	w:= &kafka.Writer {
-		Addr: kafka.TCP(config.Kafka.Broker),
-		Topic: config.Kafka.TopicName,
-		AllowAutoTopicCreation: true,
+	command.AddRoute | command.AddUser | command.RenameRouteById | command.RenameRouteByToken | command.DeleteRoute |
+		command.AddRouteWaypoint | command.MoveWaypoint | command.RenameWaypoint | command.DeleteWaypoint | command.ReorderWaypoints |
+		command.SimplifyRoute | command.AppendTrackPoints
+	UserKey() string
+}
+
+// Long-lived writer of command messages, safe for concurrent use
+//
+type Producer struct {
+	writer *kafka.Writer
+}
+
+// Creates producer with the settings from config.Kafka.Producer
+//
+func NewProducer(config abstract.Config) (*Producer, error) {
+	producerConfig := config.Kafka.Producer
+
+	requiredAcks := kafka.RequireAll
+	if producerConfig.RequiredAcks != "" {
+		if err := requiredAcks.UnmarshalText([]byte(producerConfig.RequiredAcks)); err != nil {
+			return nil, err
+		}
 	}
 
-	var jsonMessage, errM = json.Marshal(command)
-	if errM != nil{
-		log.Error().Err(errM).Msg("Unabe to marshal command to JSON")
-		return
+	var compression kafka.Compression
+	if producerConfig.Compression != "" && producerConfig.Compression != "none" {
+		if err := compression.UnmarshalText([]byte(producerConfig.Compression)); err != nil {
+			return nil, err
+		}
 	}
 
-	err := w.WriteMessages(
-		context.Background(),
-		kafka.Message {
-			Key: []byte(ksuid.New().String()),
-			Value: jsonMessage,
-			Headers: []kafka.Header {{
-				Key: "command",
-				Value: []byte(commandType),
-			}},
+	return &Producer{
+		writer: &kafka.Writer {
+			Addr: kafka.TCP(config.Kafka.Broker),
+			Topic: config.Kafka.TopicName,
+			AllowAutoTopicCreation: true,
+			Balancer: &kafka.CRC32Balancer{},
+			RequiredAcks: requiredAcks,
+			BatchSize: producerConfig.BatchSize,
+			BatchTimeout: producerConfig.BatchTimeout,
+			Compression: compression,
+			WriteTimeout: producerConfig.WriteTimeout,
+			ReadTimeout: producerConfig.ReadTimeout,
 		},
-	)
+	}, nil
+}
+
+// Flushes pending messages and closes connections
+//
+func (producer *Producer) Close() {
+	log.Debug().Msg("Close kafka producer")
+	if err := producer.writer.Close(); err != nil {
+		log.Error().Err(err).Msg("Error close kafka connection")
+	}
+}
+
+// Publishes the command and returns its id. Returns error if the message has not been accepted by the broker.
+// The trace context of ctx is passed to the processor in the message headers
+//
+func SendCommand[T ICommand](ctx context.Context, producer *Producer, commandType string, cmd T) (string, error) {
+	jsonMessage, err := json.Marshal(cmd)
 	if err != nil {
-		log.Error().Err(err).Msg("Error push message to Kafka")
+		log.Error().Err(err).Msg("Unabe to marshal command to JSON")
+		return "", err
 	}
-	if err := w.Close(); err != nil {
-		log.Error().Err(err).Msg("Error close kafka connection")
+
+	commandId := ksuid.New().String()
+	ctx, span := tracer.Start(ctx, "send " + commandType,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "kafka"),
+			attribute.String("messaging.destination", producer.writer.Topic),
+			attribute.String("command", commandType),
+			attribute.String("command.id", commandId)))
+	defer span.End()
+
+	message := kafka.Message {
+		Key: []byte(cmd.UserKey()),
+		Value: jsonMessage,
+		Headers: []kafka.Header {
+			{ Key: command.HeaderCommand, Value: []byte(commandType) },
+			{ Key: command.HeaderCommandId, Value: []byte(commandId) },
+		},
+	}
+	telemetry.InjectMessage(ctx, &message)
+
+	err = producer.writer.WriteMessages(ctx, message)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		log.Error().Err(err).Msg("Error push message to Kafka")
+		return "", err
 	}
 
-}
\ No newline at end of file
+	return commandId, nil
+}
@@ -1,7 +1,13 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gin-contrib/logger"
 	"github.com/gin-gonic/gin"
@@ -9,6 +15,7 @@ import (
 	"github.com/rs/zerolog/log"
 
 	"IB.YasDataApi/abstract"
+	"IB.YasDataApi/cmd/yas_rest/kafka"
 	"IB.YasDataApi/cmd/yas_rest/rest_api"
 	"IB.YasDataApi/dal"
 	"IB.YasDataApi/telemetry"
@@ -40,11 +47,23 @@ func main() {
 	
 	// Setup data access layer
 	//
-	dataLayer := dal.New(config)
+	dataLayer, err := dal.New(config)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Fatal: unable to setup database pool")
+	}
+	defer dataLayer.Close()
+
+	// Setup kafka producer
+	//
+	producer, err := kafka.NewProducer(config)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Fatal: unable to setup kafka producer")
+	}
+	defer producer.Close()
 
 	// Setup http routes
 	//
-	rest_api := rest_api.New(config, dataLayer)
+	rest_api := rest_api.New(config, dataLayer, producer)
 	
 	// Setup & run http server
 	//
@@ -56,16 +75,57 @@ func main() {
 	})))
 	router.Use(gin.Recovery())
 
-	tel, _ := telemetry.Setup(config)
+	tel, _ := telemetry.Setup(config, "IB.YasDataReader/restapi")
 	defer tel.Shutdown()
 
+	if err := tel.RegisterDbPoolMetrics(dataLayer.Pool); err != nil {
+		log.Error().Err(err).Msg("Unable to register database pool metrics")
+	}
+
 	router.Use(telemetry.Middleware(tel))
 
 	router.GET("/user-store/users/:telegramId", rest_api.GetUser)
 	router.GET("/route-store/users/:token/routes", rest_api.GetRouteList)
+	router.GET("/route-store/users/:token/routes.gpx", rest_api.GetRouteListGpx)
+	router.POST("/route-store/users/:token/routes", rest_api.AddRoute)
+	router.POST("/route-store/users/:token/routes/import", rest_api.ImportRoute)
+	router.GET("/route-store/users/:token/routes/:routeId", rest_api.GetRoute)
+	router.GET("/route-store/users/:token/routes/:routeId/waypoints/:waypointId", rest_api.GetWaypoint)
+	router.GET("/route-store/users/:token/routes/:routeId/plan", rest_api.GetRoutePlan)
+	router.POST("/route-store/users/:token/routes/:routeId/simplify", rest_api.SimplifyRoute)
+	router.POST("/route-store/users/:token/routes/:routeId/waypoints", rest_api.AddWaypoint)
+	router.PUT("/route-store/users/:token/routes/:routeId/waypoints", rest_api.ReorderWaypoints)
+	router.PUT("/route-store/users/:token/routes/:routeId/waypoints/:waypointId/position", rest_api.MoveWaypoint)
+	router.PUT("/route-store/users/:token/routes/:routeId/waypoints/:waypointId/name", rest_api.RenameWaypoint)
+	router.DELETE("/route-store/users/:token/routes/:routeId/waypoints/:waypointId", rest_api.DeleteWaypoint)
 	router.PUT("/route-store/users/:token/routes/:routeId", rest_api.UpdateRoute)
 	router.DELETE("/route-store/users/:token/routes/:routeId", rest_api.DeleteRoute)
-	
-	
-	router.Run(config.Listener.GetListener())
+	router.GET("/route-store/users/:token/tracks", rest_api.GetTrackList)
+	router.POST("/route-store/users/:token/tracks", rest_api.AppendTrackPoints)
+	router.GET("/route-store/users/:token/tracks/:trackId", rest_api.GetTrack)
+	router.GET("/commands/:commandId", rest_api.GetCommand)
+
+	server := &http.Server{
+		Addr: config.Listener.GetListener(),
+		Handler: router,
+	}
+
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal().Err(err).Msg("Fatal: http server failed")
+		}
+	}()
+
+	// Wait for termination signal and shutdown gracefully
+	//
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+
+	log.Info().Msg("Shutting down http server")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10 * time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Error().Err(err).Msg("Unable to shutdown http server gracefully")
+	}
 }
\ No newline at end of file
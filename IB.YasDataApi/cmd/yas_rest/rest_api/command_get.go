@@ -0,0 +1,43 @@
+package rest_api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+type GetCommandParams struct {
+	CommandId string `uri:"commandId" binding:"required,len=27,alphanum"`
+}
+
+// Returns URL of the command status
+//
+func CommandLocation(commandId string) string {
+	return "/commands/" + commandId
+}
+
+// Returns processing status of the command. Pending is the command sent less than the command
+// retention ago and not processed yet, unknown commands older than that are 404
+//
+func (rest *Rest) GetCommand (context *gin.Context) {
+
+		var params GetCommandParams
+		if err := context.ShouldBindUri(&params); err != nil {
+			log.Error().Err(err).Msg("Wrong command id")
+			context.JSON(http.StatusBadRequest, gin.H{"msg": "Wrong command id", "error": err.Error()})
+			return
+		}
+
+		status, err := rest.DataLayer.QueryCommandStatus(context.Request.Context(), params.CommandId)
+		if respondNotFound(context, err) {
+			return
+		}
+		if err != nil {
+			log.Error().Err(err).Msg("Unable to get command status")
+			context.JSON(http.StatusBadRequest, gin.H{"msg": "Unable to get command status", "error": err.Error()})
+			return
+		}
+
+		context.JSON(http.StatusOK, status)
+}
@@ -0,0 +1,42 @@
+package rest_api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+
+	"IB.YasDataApi/abstract"
+)
+
+// Response header with the cursor of the next route list page, missing on the last page
+//
+const headerNextCursor = "X-Next-Cursor"
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// Cursor is opaque to the clients: base64url of the JSON position in the list
+//
+func encodeCursor(cursor *abstract.RouteCursor) string {
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// Returns nil for the empty cursor, the cursor must belong to the sort order
+//
+func decodeCursor(value string, sort string) (*abstract.RouteCursor, error) {
+	if value == "" {
+		return nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	var cursor abstract.RouteCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.Sort != sort {
+		return nil, errInvalidCursor
+	}
+	return &cursor, nil
+}
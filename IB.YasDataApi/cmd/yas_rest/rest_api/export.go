@@ -0,0 +1,113 @@
+package rest_api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"IB.YasDataApi/abstract"
+	"IB.YasDataApi/routeformat"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// Route file formats, named by the file extension
+//
+const (
+	formatGpx = ".gpx"
+	formatKml = ".kml"
+	formatKmz = ".kmz"
+	formatCsv = ".csv"
+	formatNmea = ".nmea"
+)
+
+const (
+	mimeGpx = "application/gpx+xml"
+	mimeKml = "application/vnd.google-earth.kml+xml"
+	mimeKmz = "application/vnd.google-earth.kmz"
+	mimeGeoJson = "application/geo+json"
+	mimeNmea = "text/plain; charset=us-ascii"
+	mimeCompact = "application/vnd.yas.routes.v1"
+)
+
+type exportFormat struct {
+	contentType string
+	write func(io.Writer, string, []abstract.Route) error
+}
+
+// Formats routes can be exported to
+//
+var exportFormats = map[string]exportFormat {
+	formatGpx: { contentType: mimeGpx, write: routeformat.WriteGpx },
+	formatKml: { contentType: mimeKml, write: routeformat.WriteKml },
+	formatKmz: { contentType: mimeKmz, write: routeformat.WriteKmz },
+}
+
+// Splits route id path parameter like "123.gpx" into the route id and the format extension
+//
+func splitRouteFormat(param string) (int32, string, error) {
+	format := strings.ToLower(path.Ext(param))
+	routeId, err := strconv.ParseInt(strings.TrimSuffix(param, path.Ext(param)), 10, 32)
+	if err != nil || routeId <= 0 {
+		return 0, "", fmt.Errorf("wrong route id: %s", param)
+	}
+	return int32(routeId), format, nil
+}
+
+// Returns the route name to use as the file name, routes without name are named by id
+//
+func routeFileName(route abstract.Route) string {
+	if name := strings.TrimSpace(route.RouteName); name != "" {
+		return name
+	}
+	return fmt.Sprintf("route-%d", route.RouteId)
+}
+
+// Responds with routes in the export format as an attachment
+//
+func respondRoutes(context *gin.Context, format string, name string, routes []abstract.Route) {
+	export := exportFormats[format]
+	var buffer bytes.Buffer
+	if err := export.write(&buffer, name, routes); err != nil {
+		log.Error().Err(err).Str("format", format).Msg("Unable to write routes")
+		context.JSON(http.StatusInternalServerError, gin.H{"msg": "Unable to write routes", "error": err.Error()})
+		return
+	}
+	respondFile(context, name + format, export.contentType, buffer.Bytes())
+}
+
+// Responds with NMEA 0183 sentences as an attachment
+//
+func respondNmea(context *gin.Context, name string, routes []abstract.Route, options routeformat.NmeaOptions) {
+	var buffer bytes.Buffer
+	if err := routeformat.WriteNmea(&buffer, routes, options); err != nil {
+		log.Error().Err(err).Msg("Unable to write NMEA")
+		context.JSON(http.StatusInternalServerError, gin.H{"msg": "Unable to write NMEA", "error": err.Error()})
+		return
+	}
+	respondFile(context, name + formatNmea, mimeNmea, buffer.Bytes())
+}
+
+// Responds with GeoJSON document
+//
+func respondGeoJson(context *gin.Context, collection routeformat.FeatureCollection) {
+	data, err := json.Marshal(collection)
+	if err != nil {
+		log.Error().Err(err).Msg("Unable to write GeoJSON")
+		context.JSON(http.StatusInternalServerError, gin.H{"msg": "Unable to write GeoJSON", "error": err.Error()})
+		return
+	}
+	context.Data(http.StatusOK, mimeGeoJson, data)
+}
+
+func respondFile(context *gin.Context, fileName string, contentType string, data []byte) {
+	fileName = strings.NewReplacer("/", "_", "\\", "_").Replace(fileName)
+	context.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{ "filename": fileName }))
+	context.Data(http.StatusOK, contentType, data)
+}
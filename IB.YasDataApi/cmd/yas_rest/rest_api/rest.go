@@ -1,18 +1,45 @@
 package rest_api
 
 import (
+	"errors"
+	"net/http"
+
 	"IB.YasDataApi/abstract"
+	"IB.YasDataApi/cmd/yas_rest/kafka"
 	"IB.YasDataApi/dal"
+	"github.com/gin-gonic/gin"
 )
 
 type Rest struct {
 	Config abstract.Config
 	DataLayer dal.Dal
+	Producer *kafka.Producer
 }
 
-func New(config abstract.Config, dataLayer dal.Dal) Rest {
+func New(config abstract.Config, dataLayer dal.Dal, producer *kafka.Producer) Rest {
 	return Rest {
 		Config: config,
 		DataLayer: dataLayer,
+		Producer: producer,
 	}
-}
\ No newline at end of file
+}
+
+// Responds with 404 and tells which part of the path is unknown if err is one of dal not found errors
+//
+func respondNotFound(context *gin.Context, err error) bool {
+	switch {
+		case errors.Is(err, dal.ErrUserNotFound):
+			context.JSON(http.StatusNotFound, gin.H{"msg": "No User has been found", "error": err.Error()})
+		case errors.Is(err, dal.ErrRouteNotFound):
+			context.JSON(http.StatusNotFound, gin.H{"msg": "No Route has been found", "error": err.Error()})
+		case errors.Is(err, dal.ErrWaypointNotFound):
+			context.JSON(http.StatusNotFound, gin.H{"msg": "No Waypoint has been found", "error": err.Error()})
+		case errors.Is(err, dal.ErrTrackNotFound):
+			context.JSON(http.StatusNotFound, gin.H{"msg": "No Track has been found", "error": err.Error()})
+		case errors.Is(err, dal.ErrCommandNotFound):
+			context.JSON(http.StatusNotFound, gin.H{"msg": "No Command has been found", "error": err.Error()})
+		default:
+			return false
+	}
+	return true
+}
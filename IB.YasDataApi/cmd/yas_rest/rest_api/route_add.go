@@ -0,0 +1,133 @@
+package rest_api
+
+import (
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"IB.YasDataApi/abstract"
+	"IB.YasDataApi/abstract/command"
+	"IB.YasDataApi/cmd/yas_rest/kafka"
+	"IB.YasDataApi/navigation"
+	"IB.YasDataApi/routeformat"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v4"
+	"github.com/rs/zerolog/log"
+)
+
+const defaultRouteName = "New route"
+
+type AddRouteParams struct {
+	UserToken string `uri:"token" binding:"required,min=7,max=11"`
+	RouteName string `form:"routeName" binding:"max=256"`
+	RouteShapeParams
+}
+
+// Accepts GPX, KML, KMZ or Expedition CSV file as multipart form field "file" or as raw body and publishes add-route command.
+// The route name is taken from the routeName query parameter, the name in the file or the file name.
+// Tracks are simplified or densified before publishing by the maxPoints, toleranceMeters and densifyNm parameters
+//
+func (rest *Rest) AddRoute (context *gin.Context) {
+
+		var params AddRouteParams
+		if err := context.ShouldBindUri(&params); err != nil {
+			log.Error().Err(err).Msg("Wrong URL params")
+			context.JSON(http.StatusBadRequest, gin.H{"msg": "Wrong URL params", "error": err.Error()})
+			return
+		}
+
+		if err := context.ShouldBindQuery(&params); err != nil {
+			log.Error().Err(err).Msg("Wrong query params")
+			context.JSON(http.StatusBadRequest, gin.H{"msg": "Wrong query params", "error": err.Error()})
+			return
+		}
+
+		data, fileName, err := readRouteFile(context)
+		if err != nil {
+			log.Error().Err(err).Msg("Unable to read route file")
+			context.JSON(uploadErrorStatus(err), gin.H{"msg": "Unable to read route file", "error": err.Error()})
+			return
+		}
+
+		format := routeFileFormat(fileName, context.ContentType())
+		route, err := routeformat.Parse(format, data)
+		if err != nil {
+			log.Error().Err(err).Msg("Wrong route file")
+			context.JSON(http.StatusBadRequest, gin.H{"msg": "Wrong route file", "error": err.Error()})
+			return
+		}
+
+		rest.publishRoute(context, params, route, format, fileName)
+}
+
+// Publishes add-route command with the parsed route on behalf of the token owner
+//
+func (rest *Rest) publishRoute(context *gin.Context, params AddRouteParams, route abstract.Route, format string, fileName string) {
+
+		if options := params.options(); !options.IsZero() {
+			waypoints, err := navigation.Reshape(route.Waypoints, options, routeformat.MaxWaypoints)
+			if err != nil {
+				context.JSON(http.StatusBadRequest, gin.H{"msg": "Unable to simplify the route", "error": err.Error()})
+				return
+			}
+			route.Waypoints = waypoints
+		}
+
+		user, err := rest.DataLayer.QueryUserByToken(context.Request.Context(), params.UserToken)
+		if err == pgx.ErrNoRows {
+			context.JSON(http.StatusNotFound, gin.H{"msg": "No User has been found"})
+			return
+		}
+		if err != nil {
+			log.Error().Err(err).Msg("Unable to get user")
+			context.JSON(http.StatusBadRequest, gin.H{"msg": "Unable to get user", "error": err.Error()})
+			return
+		}
+
+		commandId, err := kafka.SendCommand (
+			context.Request.Context(),
+			rest.Producer,
+			command.CmdAddRoute,
+			command.AddRoute {
+				UserId: int64(user.UserId),
+				Token: params.UserToken,
+				RouteName: routeName(params.RouteName, route.RouteName, fileName),
+				Waypoints: toAddWaypoints(route.Waypoints),
+		})
+		if err != nil {
+			context.JSON(http.StatusServiceUnavailable, gin.H{"msg": "Unable to add the route", "error": err.Error()})
+			return
+		}
+
+		context.Header("Location", CommandLocation(commandId))
+		context.JSON(http.StatusAccepted, gin.H{
+			"msg": "The route upload has been accepted",
+			"commandId": commandId,
+			"waypoints": len(route.Waypoints),
+			"format": format,
+		})
+}
+
+// Returns the first non-empty name, the file name is used without extension
+//
+func routeName(queryName string, fileRouteName string, fileName string) string {
+	fileName = strings.TrimSuffix(filepath.Base(fileName), filepath.Ext(fileName))
+	for _, name := range []string{ queryName, fileRouteName, fileName } {
+		if name = strings.TrimSpace(name); name != "" && name != "." {
+			return name
+		}
+	}
+	return defaultRouteName
+}
+
+func toAddWaypoints(waypoints []abstract.Waypoint) []command.AddWaypoint {
+	addWaypoints := make([]command.AddWaypoint, 0, len(waypoints))
+	for _, wp := range waypoints {
+		addWaypoints = append(addWaypoints, command.AddWaypoint {
+			WaypointName: wp.WaypointName,
+			Lat: wp.Lat,
+			Lon: wp.Lon,
+		})
+	}
+	return addWaypoints
+}
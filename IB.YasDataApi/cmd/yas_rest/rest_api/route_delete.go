@@ -23,13 +23,19 @@ func (rest *Rest) DeleteRoute (context *gin.Context) {
 			return
 		}
 
-		kafka.SendCommand (
-			rest.Config, 
-			command.CmdDeleteRoute, 
+		commandId, err := kafka.SendCommand (
+			context.Request.Context(),
+			rest.Producer,
+			command.CmdDeleteRoute,
 			command.DeleteRoute {
 				Token: params.UserToken,
 				RouteId: params.RouteId,
 		})
+		if err != nil {
+			context.JSON(http.StatusServiceUnavailable, gin.H{"msg": "Unable to delete the route", "error": err.Error()})
+			return
+		}
 
-		context.JSON(http.StatusOK, gin.H{"msg": "The route has been successfully deleted"})
+		context.Header("Location", CommandLocation(commandId))
+		context.JSON(http.StatusAccepted, gin.H{"msg": "The route deletion has been accepted", "commandId": commandId})
 }
\ No newline at end of file
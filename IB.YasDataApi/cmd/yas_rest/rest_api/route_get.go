@@ -0,0 +1,73 @@
+package rest_api
+
+import (
+	"net/http"
+
+	"IB.YasDataApi/abstract"
+	"IB.YasDataApi/routeformat"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+type GetRouteParams struct {
+	UserToken string `uri:"token" binding:"required,min=6,max=10"`
+	RouteId string `uri:"routeId" binding:"required"`
+	TalkerId string `form:"talker" binding:"omitempty,len=2,alpha,uppercase"`
+	RouteStatsParams
+}
+
+// Returns the route as JSON or in the format of the route id extension, e.g. /routes/123.gpx or /routes/123.kmz.
+// NMEA output (/routes/123.nmea) uses the talker id of the talker query parameter, GP by default.
+// With ?stats=true JSON route carries the total distance and the legs,
+// with ?magnetic=true also the magnetic variation and bearings
+//
+func (rest *Rest) GetRoute (context *gin.Context) {
+
+		var params GetRouteParams
+		if err := context.ShouldBindUri(&params); err != nil {
+			log.Error().Err(err).Msg("Wrong URL params")
+			context.JSON(http.StatusBadRequest, gin.H{"msg": "Wrong URL params", "error": err.Error()})
+			return
+		}
+
+		if err := context.ShouldBindQuery(&params); err != nil {
+			log.Error().Err(err).Msg("Wrong query params")
+			context.JSON(http.StatusBadRequest, gin.H{"msg": "Wrong query params", "error": err.Error()})
+			return
+		}
+
+		routeId, format, err := splitRouteFormat(params.RouteId)
+		if err != nil {
+			log.Error().Err(err).Msg("Wrong route id")
+			context.JSON(http.StatusBadRequest, gin.H{"msg": "Wrong route id", "error": err.Error()})
+			return
+		}
+		if _, ok := exportFormats[format]; !ok && format != formatNmea && format != "" {
+			context.JSON(http.StatusNotFound, gin.H{"msg": "Unsupported route format", "format": format})
+			return
+		}
+
+		route, err := rest.DataLayer.QueryRoute(context.Request.Context(), params.UserToken, routeId)
+		if respondNotFound(context, err) {
+			return
+		}
+		if err != nil {
+			log.Error().Err(err).Msg("Unable to get route")
+			context.JSON(http.StatusBadRequest, gin.H{"msg": "Unable to get route", "error": err.Error()})
+			return
+		}
+
+		if format == "" {
+			routes := []abstract.Route{ route }
+			if !addRouteStatistics(context, routes, params.RouteStatsParams) {
+				return
+			}
+			context.JSON(http.StatusOK, routes[0])
+			return
+		}
+		if format == formatNmea {
+			respondNmea(context, routeFileName(route), []abstract.Route{ route }, routeformat.NmeaOptions{ TalkerId: params.TalkerId })
+			return
+		}
+		respondRoutes(context, format, routeFileName(route), []abstract.Route{ route })
+}
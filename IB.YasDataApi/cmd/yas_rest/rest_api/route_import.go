@@ -0,0 +1,51 @@
+package rest_api
+
+import (
+	"net/http"
+
+	"IB.YasDataApi/routeformat"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// Accepts route file of any supported format: GPX, OpenCPN GPX, KML, KMZ or Expedition CSV.
+// The format is detected by the content, the file name and the content type are ignored
+//
+func (rest *Rest) ImportRoute (context *gin.Context) {
+
+		var params AddRouteParams
+		if err := context.ShouldBindUri(&params); err != nil {
+			log.Error().Err(err).Msg("Wrong URL params")
+			context.JSON(http.StatusBadRequest, gin.H{"msg": "Wrong URL params", "error": err.Error()})
+			return
+		}
+
+		if err := context.ShouldBindQuery(&params); err != nil {
+			log.Error().Err(err).Msg("Wrong query params")
+			context.JSON(http.StatusBadRequest, gin.H{"msg": "Wrong query params", "error": err.Error()})
+			return
+		}
+
+		data, fileName, err := readRouteFile(context)
+		if err != nil {
+			log.Error().Err(err).Msg("Unable to read route file")
+			context.JSON(uploadErrorStatus(err), gin.H{"msg": "Unable to read route file", "error": err.Error()})
+			return
+		}
+
+		format, err := routeformat.DetectFormat(data)
+		if err != nil {
+			log.Error().Err(err).Msg("Unknown route file format")
+			context.JSON(http.StatusUnsupportedMediaType, gin.H{"msg": "Unknown route file format", "error": err.Error()})
+			return
+		}
+
+		route, err := routeformat.Parse(format, data)
+		if err != nil {
+			log.Error().Err(err).Str("format", format).Msg("Wrong route file")
+			context.JSON(http.StatusBadRequest, gin.H{"msg": "Wrong route file", "format": format, "error": err.Error()})
+			return
+		}
+
+		rest.publishRoute(context, params, route, format, fileName)
+}
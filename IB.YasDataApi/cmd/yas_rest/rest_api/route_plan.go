@@ -0,0 +1,112 @@
+package rest_api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"IB.YasDataApi/navigation"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+type RoutePlanParams struct {
+	UserToken string `uri:"token" binding:"required,min=6,max=10"`
+	RouteId int32 `uri:"routeId" binding:"required"`
+}
+
+// Speed limits of the plan, the same as the speedKn binding
+//
+const (
+	minPlanSpeedKn = 0.1
+	maxPlanSpeedKn = 100
+)
+
+// Start is RFC 3339, now by default. Leg speeds are comma separated leg:speed pairs,
+// e.g. 1:4.5,3:8 sails the first leg at 4.5 kn and the third one at 8 kn
+//
+type RoutePlanQuery struct {
+	SpeedKn float64 `form:"speedKn" binding:"required,gte=0.1,max=100"`
+	Start time.Time `form:"start" time_format:"2006-01-02T15:04:05Z07:00"`
+	LegSpeeds string `form:"legSpeeds"`
+}
+
+// Returns ETA, cumulative time and distance of every waypoint of the route at the boat speed,
+// see navigation.PlanRoute
+//
+func (rest *Rest) GetRoutePlan (context *gin.Context) {
+
+		var params RoutePlanParams
+		if err := context.ShouldBindUri(&params); err != nil {
+			log.Error().Err(err).Msg("Wrong URL params")
+			context.JSON(http.StatusBadRequest, gin.H{"msg": "Wrong URL params", "error": err.Error()})
+			return
+		}
+
+		var query RoutePlanQuery
+		if err := context.ShouldBindQuery(&query); err != nil {
+			log.Error().Err(err).Msg("Wrong query params")
+			context.JSON(http.StatusBadRequest, gin.H{"msg": "Wrong query params", "error": err.Error()})
+			return
+		}
+		legSpeeds, err := parseLegSpeeds(query.LegSpeeds)
+		if err != nil {
+			context.JSON(http.StatusBadRequest, gin.H{"msg": "Wrong leg speeds", "error": err.Error()})
+			return
+		}
+		if query.Start.IsZero() {
+			query.Start = time.Now().UTC().Truncate(time.Second)
+		}
+
+		route, err := rest.DataLayer.QueryRoute(context.Request.Context(), params.UserToken, params.RouteId)
+		if respondNotFound(context, err) {
+			return
+		}
+		if err != nil {
+			log.Error().Err(err).Msg("Unable to get route")
+			context.JSON(http.StatusBadRequest, gin.H{"msg": "Unable to get route", "error": err.Error()})
+			return
+		}
+
+		plan, err := navigation.PlanRoute(route, navigation.PlanOptions {
+			SpeedKn: query.SpeedKn,
+			Start: query.Start,
+			LegSpeedsKn: legSpeeds,
+		})
+		if err != nil {
+			context.JSON(http.StatusBadRequest, gin.H{"msg": "Unable to plan the route", "error": err.Error()})
+			return
+		}
+
+		context.JSON(http.StatusOK, plan)
+}
+
+// Parses leg:speed pairs, the speed of the same leg may be given once
+//
+func parseLegSpeeds(value string) (map[int]float64, error) {
+	legSpeeds := map[int]float64{}
+	if strings.TrimSpace(value) == "" {
+		return legSpeeds, nil
+	}
+	for _, pair := range strings.Split(value, ",") {
+		legValue, speedValue, found := strings.Cut(strings.TrimSpace(pair), ":")
+		if !found {
+			return nil, fmt.Errorf("%q is not leg:speed", pair)
+		}
+		leg, err := strconv.Atoi(legValue)
+		if err != nil {
+			return nil, fmt.Errorf("%q: wrong leg number", pair)
+		}
+		speed, err := strconv.ParseFloat(speedValue, 64)
+		if err != nil || !(speed >= minPlanSpeedKn && speed <= maxPlanSpeedKn) {
+			return nil, fmt.Errorf("%q: speed must be from %v to %v kn", pair, minPlanSpeedKn, maxPlanSpeedKn)
+		}
+		if _, ok := legSpeeds[leg]; ok {
+			return nil, fmt.Errorf("leg %d is given twice", leg)
+		}
+		legSpeeds[leg] = speed
+	}
+	return legSpeeds, nil
+}
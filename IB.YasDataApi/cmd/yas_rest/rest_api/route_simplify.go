@@ -0,0 +1,75 @@
+package rest_api
+
+import (
+	"net/http"
+
+	"IB.YasDataApi/abstract/command"
+	"IB.YasDataApi/cmd/yas_rest/kafka"
+	"IB.YasDataApi/navigation"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// Simplification and densification of the route, query parameters of add-route
+// and the body of simplify. See navigation.ShapeOptions
+//
+type RouteShapeParams struct {
+	MaxPoints int `form:"maxPoints" json:"maxPoints" binding:"omitempty,min=2,max=5000"`
+	ToleranceMeters float64 `form:"toleranceMeters" json:"toleranceMeters" binding:"omitempty,gt=0,max=100000"`
+	DensifyNm float64 `form:"densifyNm" json:"densifyNm" binding:"omitempty,min=0.1,max=10000"`
+}
+
+func (params RouteShapeParams) options() navigation.ShapeOptions {
+	return navigation.ShapeOptions {
+		MaxPoints: params.MaxPoints,
+		ToleranceMeters: params.ToleranceMeters,
+		DensifyNm: params.DensifyNm,
+	}
+}
+
+type SimplifyRouteParams struct {
+	UserToken string `uri:"token" binding:"required,min=7,max=11"`
+	RouteId int32 `uri:"routeId" binding:"required"`
+}
+
+// Publishes simplify-route command which rewrites the stored waypoints of the route
+//
+func (rest *Rest) SimplifyRoute (context *gin.Context) {
+
+		var params SimplifyRouteParams
+		if err := context.ShouldBindUri(&params); err != nil {
+			log.Error().Err(err).Msg("Wrong URL params")
+			context.JSON(http.StatusBadRequest, gin.H{"msg": "Wrong URL params", "error": err.Error()})
+			return
+		}
+
+		var body RouteShapeParams
+		if err := context.ShouldBindJSON(&body); err != nil {
+			log.Error().Err(err).Msg("Wrong JSON params")
+			context.JSON(http.StatusBadRequest, gin.H{"msg": "Wrong JSON params", "error": err.Error()})
+			return
+		}
+		if body.options().IsZero() {
+			context.JSON(http.StatusBadRequest, gin.H{"msg": "Wrong JSON params", "error": "maxPoints, toleranceMeters or densifyNm is required"})
+			return
+		}
+
+		commandId, err := kafka.SendCommand (
+			context.Request.Context(),
+			rest.Producer,
+			command.CmdSimplifyRoute,
+			command.SimplifyRoute {
+				Token: params.UserToken,
+				RouteId: params.RouteId,
+				MaxPoints: body.MaxPoints,
+				ToleranceMeters: body.ToleranceMeters,
+				DensifyNm: body.DensifyNm,
+		})
+		if err != nil {
+			context.JSON(http.StatusServiceUnavailable, gin.H{"msg": "Unable to simplify the route", "error": err.Error()})
+			return
+		}
+
+		context.Header("Location", CommandLocation(commandId))
+		context.JSON(http.StatusAccepted, gin.H{"msg": "The route simplification has been accepted", "commandId": commandId})
+}
@@ -0,0 +1,50 @@
+package rest_api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"IB.YasDataApi/abstract"
+	"IB.YasDataApi/navigation"
+	"IB.YasDataApi/wmm"
+	"github.com/gin-gonic/gin"
+)
+
+// Query flags of the route statistics. Magnetic bearings need the legs, so magnetic=true
+// turns the statistics on. The variation is computed for the date, today by default
+//
+type RouteStatsParams struct {
+	Stats bool `form:"stats"`
+	Magnetic bool `form:"magnetic"`
+	Date time.Time `form:"date" time_format:"2006-01-02"`
+}
+
+// Adds the requested statistics to the routes. Responds 400 and returns false
+// if the date is outside of the magnetic model
+//
+func addRouteStatistics(context *gin.Context, routes []abstract.Route, params RouteStatsParams) bool {
+	if !params.Stats && !params.Magnetic {
+		return true
+	}
+	date := params.Date
+	if date.IsZero() {
+		date = time.Now().UTC()
+	}
+
+	for i := range routes {
+		navigation.AddStatistics(&routes[i])
+		if !params.Magnetic {
+			continue
+		}
+		if err := navigation.AddMagnetic(&routes[i], date); err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, wmm.ErrOutOfRange) {
+				status = http.StatusBadRequest
+			}
+			context.JSON(status, gin.H{"msg": "Unable to compute magnetic variation", "error": err.Error()})
+			return false
+		}
+	}
+	return true
+}
@@ -30,14 +30,20 @@ func (rest *Rest) UpdateRoute (context *gin.Context) {
 			return
 		}
 
-		kafka.SendCommand (
-			rest.Config, 
-			command.CmdRenameRouteByToken, 
+		commandId, err := kafka.SendCommand (
+			context.Request.Context(),
+			rest.Producer,
+			command.CmdRenameRouteByToken,
 			command.RenameRouteByToken {
 				Token: params.UserToken,
 				RouteId: params.RouteId,
 				RouteName: params.RouteName,
 		})
+		if err != nil {
+			context.JSON(http.StatusServiceUnavailable, gin.H{"msg": "Unable to update the route", "error": err.Error()})
+			return
+		}
 
-		context.JSON(http.StatusOK, gin.H{"msg": "The route has been successfully updated"})
+		context.Header("Location", CommandLocation(commandId))
+		context.JSON(http.StatusAccepted, gin.H{"msg": "The route update has been accepted", "commandId": commandId})
 }
\ No newline at end of file
@@ -2,16 +2,38 @@ package rest_api
 
 import (
 	"net/http"
+	"time"
 
+	"IB.YasDataApi/abstract"
+	"IB.YasDataApi/routeformat"
 	"github.com/gin-gonic/gin"
 	"github.com/rs/zerolog/log"
 )
 
+// Upload time bounds are RFC 3339, from is inclusive and to is exclusive.
+// Zero waypoint bounds do not filter
+//
 type RouteListParams struct {
 	UserToken string 	`uri:"token" binding:"required,min=6,max=10"`
-	Limit int32			`form:"limit"`
+	Limit int32			`form:"limit" binding:"min=0"`
+	Format string		`form:"format" binding:"omitempty,oneof=json geojson"`
+	Cursor string		`form:"cursor"`
+	Sort string			`form:"sort" binding:"omitempty,oneof=newest oldest name"`
+	Name string			`form:"name" binding:"max=256"`
+	From time.Time		`form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To time.Time		`form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+	MinWaypoints int32	`form:"minWaypoints" binding:"min=0"`
+	MaxWaypoints int32	`form:"maxWaypoints" binding:"min=0"`
+	RouteStatsParams
 }
 
+// Returns routes of the user as JSON or, if requested by Accept: application/geo+json
+// or ?format=geojson, as GeoJSON feature collection. The watch app requests the compact
+// binary form with Accept: application/vnd.yas.routes.v1, see routeformat.EncodeCompact.
+// The list is paged by limit, the cursor of the next page is in the X-Next-Cursor header.
+// With ?stats=true JSON routes carry the total distance and the legs, see navigation.AddStatistics,
+// with ?magnetic=true also the magnetic variation and bearings, see RouteStatsParams
+//
 func (rest *Rest) GetRouteList (context *gin.Context) {
 
 		var params RouteListParams
@@ -22,23 +44,59 @@ func (rest *Rest) GetRouteList (context *gin.Context) {
 		}
 
 		if err := context.ShouldBindQuery(&params); err != nil {
-			log.Error().Err(err).Msg("Error bing limit")
-			context.JSON(http.StatusBadRequest, gin.H{"msg": "error limit binding", "error": err.Error()})
+			log.Error().Err(err).Msg("Wrong query params")
+			context.JSON(http.StatusBadRequest, gin.H{"msg": "Wrong query params", "error": err.Error()})
 			return
 		}
 
-		routes, err := rest.DataLayer.QueryRoutes(params.UserToken, params.Limit)
+		if params.Sort == "" {
+			params.Sort = abstract.RouteSortNewest
+		}
+		cursor, err := decodeCursor(params.Cursor, params.Sort)
+		if err != nil {
+			context.JSON(http.StatusBadRequest, gin.H{"msg": "Wrong cursor, it does not belong to the sort order", "error": err.Error()})
+			return
+		}
 
-		
+		page, err := rest.DataLayer.QueryRoutes(context.Request.Context(), params.UserToken, abstract.RouteQuery {
+			Limit: params.Limit,
+			Sort: params.Sort,
+			After: cursor,
+			NameContains: params.Name,
+			UploadedFrom: params.From,
+			UploadedTo: params.To,
+			MinWaypoints: params.MinWaypoints,
+			MaxWaypoints: params.MaxWaypoints,
+		})
+		if respondNotFound(context, err) {
+			return
+		}
 		if err != nil {
 			log.Error().Err(err).Msg("Unable to get route")
 			context.JSON(http.StatusBadRequest, gin.H{"msg": "Unable to get route", "error": err.Error()})
 			return
 		}
-		if routes == nil {
-			context.JSON(http.StatusNotFound, gin.H{"msg": "No User/Routes has been found"})
+		routes := page.Routes
+		if page.Next != nil {
+			context.Header(headerNextCursor, encodeCursor(page.Next))
+		}
+
+		context.Header("Vary", "Accept")
+		accepted := ""
+		if params.Format == "" {
+			accepted = context.NegotiateFormat(gin.MIMEJSON, mimeGeoJson, mimeCompact)
+		}
+		if params.Format == "geojson" || accepted == mimeGeoJson {
+			respondGeoJson(context, routeformat.ToGeoJson(routes))
+			return
+		}
+		if accepted == mimeCompact {
+			context.Data(http.StatusOK, mimeCompact, routeformat.EncodeCompact(routes))
+			return
+		}
+
+		if !addRouteStatistics(context, routes, params.RouteStatsParams) {
 			return
 		}
-		
 		context.JSON(http.StatusOK, routes)
 }
\ No newline at end of file
@@ -0,0 +1,37 @@
+package rest_api
+
+import (
+	"net/http"
+
+	"IB.YasDataApi/abstract"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+type RouteListGpxParams struct {
+	UserToken string `uri:"token" binding:"required,min=6,max=10"`
+}
+
+// Returns all routes of the user as one GPX document
+//
+func (rest *Rest) GetRouteListGpx (context *gin.Context) {
+
+		var params RouteListGpxParams
+		if err := context.ShouldBindUri(&params); err != nil {
+			log.Error().Err(err).Msg("Wrong user id")
+			context.JSON(http.StatusBadRequest, gin.H{"msg": "Wrong user id", "error": err.Error()})
+			return
+		}
+
+		page, err := rest.DataLayer.QueryRoutes(context.Request.Context(), params.UserToken, abstract.RouteQuery{})
+		if respondNotFound(context, err) {
+			return
+		}
+		if err != nil {
+			log.Error().Err(err).Msg("Unable to get route")
+			context.JSON(http.StatusBadRequest, gin.H{"msg": "Unable to get route", "error": err.Error()})
+			return
+		}
+
+		respondRoutes(context, formatGpx, "routes-" + params.UserToken, page.Routes)
+}
@@ -0,0 +1,101 @@
+package rest_api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"IB.YasDataApi/abstract/command"
+	"IB.YasDataApi/cmd/yas_rest/kafka"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// Upper limit of the uploaded batch. A batch is at most 3600 points, an hour of 1 Hz logging,
+// so the command fits into one Kafka message
+//
+const maxTrackBatchSize = 1 << 20
+
+type AppendTrackPointsParams struct {
+	UserToken string `uri:"token" binding:"required,min=7,max=11"`
+}
+
+// Track key is chosen by the watch and is the same for all batches of the track
+//
+type AppendTrackPointsBody struct {
+	TrackKey string `json:"trackKey" binding:"required,max=64"`
+	TrackName string `json:"trackName" binding:"max=256"`
+	Points []TrackPointBody `json:"points" binding:"required,min=1,max=3600,dive"`
+}
+
+// SOG is in knots, COG and heading are true degrees
+//
+type TrackPointBody struct {
+	Time time.Time `json:"time" binding:"required"`
+	Lat *float64 `json:"lat" binding:"required,min=-90,max=90"`
+	Lon *float64 `json:"lon" binding:"required,min=-180,max=180"`
+	Sog *float64 `json:"sog" binding:"omitempty,min=0,max=100"`
+	Cog *float64 `json:"cog" binding:"omitempty,min=0,lt=360"`
+	Heading *float64 `json:"heading" binding:"omitempty,min=0,lt=360"`
+}
+
+// Publishes append-track-points command with the batch of points logged by the watch.
+// Points must be in time order, the points already stored are skipped by the processor,
+// so a batch can be uploaded again after a failure
+//
+func (rest *Rest) AppendTrackPoints (context *gin.Context) {
+
+		var params AppendTrackPointsParams
+		if err := context.ShouldBindUri(&params); err != nil {
+			log.Error().Err(err).Msg("Wrong URL params")
+			context.JSON(http.StatusBadRequest, gin.H{"msg": "Wrong URL params", "error": err.Error()})
+			return
+		}
+
+		context.Request.Body = http.MaxBytesReader(context.Writer, context.Request.Body, maxTrackBatchSize)
+		var body AppendTrackPointsBody
+		if err := context.ShouldBindJSON(&body); err != nil {
+			log.Error().Err(err).Msg("Wrong JSON params")
+			status := http.StatusBadRequest
+			var maxBytesError *http.MaxBytesError
+			if errors.As(err, &maxBytesError) {
+				status = http.StatusRequestEntityTooLarge
+			}
+			context.JSON(status, gin.H{"msg": "Wrong JSON params", "error": err.Error()})
+			return
+		}
+
+		points := make([]command.TrackPoint, 0, len(body.Points))
+		for i, p := range body.Points {
+			if i > 0 && !p.Time.After(body.Points[i - 1].Time) {
+				context.JSON(http.StatusBadRequest, gin.H{"msg": "Wrong JSON params", "error": "points are not in time order", "point": i})
+				return
+			}
+			points = append(points, command.TrackPoint {
+				Time: p.Time.UTC(),
+				Lat: *p.Lat,
+				Lon: *p.Lon,
+				Sog: p.Sog,
+				Cog: p.Cog,
+				Heading: p.Heading,
+			})
+		}
+
+		commandId, err := kafka.SendCommand (
+			context.Request.Context(),
+			rest.Producer,
+			command.CmdAppendTrackPoints,
+			command.AppendTrackPoints {
+				Token: params.UserToken,
+				TrackKey: body.TrackKey,
+				TrackName: body.TrackName,
+				Points: points,
+		})
+		if err != nil {
+			context.JSON(http.StatusServiceUnavailable, gin.H{"msg": "Unable to append the track points", "error": err.Error()})
+			return
+		}
+
+		context.Header("Location", CommandLocation(commandId))
+		context.JSON(http.StatusAccepted, gin.H{"msg": "The track points have been accepted", "commandId": commandId, "points": len(points)})
+}
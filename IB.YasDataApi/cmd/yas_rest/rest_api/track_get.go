@@ -0,0 +1,69 @@
+package rest_api
+
+import (
+	"net/http"
+	"time"
+
+	"IB.YasDataApi/abstract"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// Response header with the time of the first point of the next page, missing on the last page
+//
+const headerNextFrom = "X-Next-From"
+
+// Points on one page unless the limit is given
+//
+const defaultTrackPointLimit = 10000
+
+// Point time bounds are RFC 3339, from is inclusive and to is exclusive
+//
+type GetTrackParams struct {
+	UserToken string `uri:"token" binding:"required,min=6,max=10"`
+	TrackId int32 `uri:"trackId" binding:"required"`
+	From time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+	Limit int32 `form:"limit" binding:"min=0,max=100000"`
+}
+
+// Returns the track with its points in time order. Points are paged by limit, 10000 by default:
+// the X-Next-From header has the time of the first point of the next page to be passed as from
+//
+func (rest *Rest) GetTrack (context *gin.Context) {
+
+		var params GetTrackParams
+		if err := context.ShouldBindUri(&params); err != nil {
+			log.Error().Err(err).Msg("Wrong URL params")
+			context.JSON(http.StatusBadRequest, gin.H{"msg": "Wrong URL params", "error": err.Error()})
+			return
+		}
+
+		if err := context.ShouldBindQuery(&params); err != nil {
+			log.Error().Err(err).Msg("Wrong query params")
+			context.JSON(http.StatusBadRequest, gin.H{"msg": "Wrong query params", "error": err.Error()})
+			return
+		}
+		if params.Limit == 0 {
+			params.Limit = defaultTrackPointLimit
+		}
+
+		page, err := rest.DataLayer.QueryTrack(context.Request.Context(), params.UserToken, params.TrackId, abstract.TrackPointQuery {
+			From: params.From,
+			To: params.To,
+			Limit: params.Limit,
+		})
+		if respondNotFound(context, err) {
+			return
+		}
+		if err != nil {
+			log.Error().Err(err).Msg("Unable to get track")
+			context.JSON(http.StatusBadRequest, gin.H{"msg": "Unable to get track", "error": err.Error()})
+			return
+		}
+
+		if page.Next != nil {
+			context.Header(headerNextFrom, page.Next.UTC().Format(time.RFC3339Nano))
+		}
+		context.JSON(http.StatusOK, page.Track)
+}
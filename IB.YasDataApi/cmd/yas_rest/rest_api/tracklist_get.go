@@ -0,0 +1,36 @@
+package rest_api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+type TrackListParams struct {
+	UserToken string `uri:"token" binding:"required,min=6,max=10"`
+}
+
+// Returns tracks of the user without points, the newest first
+//
+func (rest *Rest) GetTrackList (context *gin.Context) {
+
+		var params TrackListParams
+		if err := context.ShouldBindUri(&params); err != nil {
+			log.Error().Err(err).Msg("Wrong user id")
+			context.JSON(http.StatusBadRequest, gin.H{"msg": "Wrong user id", "error": err.Error()})
+			return
+		}
+
+		tracks, err := rest.DataLayer.QueryTracks(context.Request.Context(), params.UserToken)
+		if respondNotFound(context, err) {
+			return
+		}
+		if err != nil {
+			log.Error().Err(err).Msg("Unable to get tracks")
+			context.JSON(http.StatusBadRequest, gin.H{"msg": "Unable to get tracks", "error": err.Error()})
+			return
+		}
+
+		context.JSON(http.StatusOK, tracks)
+}
@@ -0,0 +1,99 @@
+package rest_api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+
+	"IB.YasDataApi/routeformat"
+	"github.com/gin-gonic/gin"
+)
+
+// Upper limit of the uploaded route file
+//
+const maxRouteFileSize = 5 << 20
+
+var errRouteFileTooLarge = errors.New("route file is too large")
+
+var errRouteFileEmpty = errors.New("route file is empty")
+
+// Reads route file either from the "file" field of multipart form or from the raw request body.
+// Returns the file content and the file name, the name is empty for the raw body
+//
+func readRouteFile(context *gin.Context) ([]byte, string, error) {
+	if context.Request.ContentLength > maxRouteFileSize {
+		return nil, "", errRouteFileTooLarge
+	}
+	context.Request.Body = http.MaxBytesReader(context.Writer, context.Request.Body, maxRouteFileSize)
+
+	var data []byte
+	fileName := ""
+	if strings.HasPrefix(context.ContentType(), "multipart/form-data") {
+		fileHeader, err := context.FormFile("file")
+		if err != nil {
+			return nil, "", uploadError(err)
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			return nil, "", err
+		}
+		defer file.Close()
+
+		if data, err = io.ReadAll(file); err != nil {
+			return nil, "", err
+		}
+		fileName = fileHeader.Filename
+	} else {
+		var err error
+		if data, err = io.ReadAll(context.Request.Body); err != nil {
+			return nil, "", uploadError(err)
+		}
+	}
+
+	if len(data) == 0 {
+		return nil, "", errRouteFileEmpty
+	}
+	return data, fileName, nil
+}
+
+// Returns format of the route file by the file extension, then by the content type.
+// Files of unknown format are GPX
+//
+func routeFileFormat(fileName string, contentType string) string {
+	switch strings.ToLower(path.Ext(fileName)) {
+		case formatKml:
+			return routeformat.FormatKml
+		case formatKmz:
+			return routeformat.FormatKmz
+		case formatCsv:
+			return routeformat.FormatExpedition
+	}
+	switch contentType {
+		case mimeKml:
+			return routeformat.FormatKml
+		case mimeKmz, "application/zip":
+			return routeformat.FormatKmz
+		case "text/csv":
+			return routeformat.FormatExpedition
+	}
+	return routeformat.FormatGpx
+}
+
+func uploadError(err error) error {
+	var maxBytesError *http.MaxBytesError
+	if errors.As(err, &maxBytesError) || strings.Contains(err.Error(), "request body too large") {
+		return errRouteFileTooLarge
+	}
+	return err
+}
+
+// Responds with 413 if the file exceeds the limit and with 400 otherwise
+//
+func uploadErrorStatus(err error) int {
+	if errors.Is(err, errRouteFileTooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
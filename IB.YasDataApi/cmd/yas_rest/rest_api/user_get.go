@@ -21,7 +21,7 @@ func (rest *Rest) GetUser (context *gin.Context) {
 			return
 		}
 
-		user, err := rest.DataLayer.QueryUser(params.TelegramId)
+		user, err := rest.DataLayer.QueryUser(context.Request.Context(), params.TelegramId)
 		if err == pgx.ErrNoRows {
 			context.JSON(http.StatusNotFound, gin.H{"msg": "No User has been found"})
 			return
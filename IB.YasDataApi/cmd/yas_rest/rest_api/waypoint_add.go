@@ -0,0 +1,61 @@
+package rest_api
+
+import (
+	"net/http"
+
+	"IB.YasDataApi/cmd/yas_rest/kafka"
+	"IB.YasDataApi/abstract/command"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+type AddWaypointParams struct {
+	UserToken string `uri:"token" binding:"required,min=7,max=11"`
+	RouteId int32 `uri:"routeId" binding:"required"`
+}
+
+// Position is the order id of the new waypoint, the waypoint is appended without it
+//
+type AddWaypointBody struct {
+	WaypointName string `json:"waypointName" binding:"max=256"`
+	Lat *float64 `json:"lat" binding:"required,min=-90,max=90"`
+	Lon *float64 `json:"lon" binding:"required,min=-180,max=180"`
+	Position *int32 `json:"position" binding:"omitempty,min=0"`
+}
+
+func (rest *Rest) AddWaypoint (context *gin.Context) {
+
+		var params AddWaypointParams
+		if err := context.ShouldBindUri(&params); err != nil {
+			log.Error().Err(err).Msg("Wrong URL params")
+			context.JSON(http.StatusBadRequest, gin.H{"msg": "Wrong URL params", "error": err.Error()})
+			return
+		}
+
+		var body AddWaypointBody
+		if err := context.ShouldBindJSON(&body); err != nil {
+			log.Error().Err(err).Msg("Wrong JSON params")
+			context.JSON(http.StatusBadRequest, gin.H{"msg": "Wrong JSON params", "error": err.Error()})
+			return
+		}
+
+		commandId, err := kafka.SendCommand (
+			context.Request.Context(),
+			rest.Producer,
+			command.CmdAddWaypoint,
+			command.AddRouteWaypoint {
+				Token: params.UserToken,
+				RouteId: params.RouteId,
+				WaypointName: body.WaypointName,
+				Lat: *body.Lat,
+				Lon: *body.Lon,
+				Position: body.Position,
+		})
+		if err != nil {
+			context.JSON(http.StatusServiceUnavailable, gin.H{"msg": "Unable to add the waypoint", "error": err.Error()})
+			return
+		}
+
+		context.Header("Location", CommandLocation(commandId))
+		context.JSON(http.StatusAccepted, gin.H{"msg": "The waypoint has been accepted", "commandId": commandId})
+}
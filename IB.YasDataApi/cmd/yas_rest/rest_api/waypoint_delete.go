@@ -0,0 +1,43 @@
+package rest_api
+
+import (
+	"net/http"
+
+	"IB.YasDataApi/cmd/yas_rest/kafka"
+	"IB.YasDataApi/abstract/command"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+type DeleteWaypointParams struct {
+	UserToken string `uri:"token" binding:"required,min=7,max=11"`
+	RouteId int32 `uri:"routeId" binding:"required"`
+	WaypointId int32 `uri:"waypointId" binding:"required"`
+}
+
+func (rest *Rest) DeleteWaypoint (context *gin.Context) {
+
+		var params DeleteWaypointParams
+		if err := context.ShouldBindUri(&params); err != nil {
+			log.Error().Err(err).Msg("Wrong URL params")
+			context.JSON(http.StatusBadRequest, gin.H{"msg": "Wrong URL params", "error": err.Error()})
+			return
+		}
+
+		commandId, err := kafka.SendCommand (
+			context.Request.Context(),
+			rest.Producer,
+			command.CmdDeleteWaypoint,
+			command.DeleteWaypoint {
+				Token: params.UserToken,
+				RouteId: params.RouteId,
+				WaypointId: params.WaypointId,
+		})
+		if err != nil {
+			context.JSON(http.StatusServiceUnavailable, gin.H{"msg": "Unable to delete the waypoint", "error": err.Error()})
+			return
+		}
+
+		context.Header("Location", CommandLocation(commandId))
+		context.JSON(http.StatusAccepted, gin.H{"msg": "The waypoint deletion has been accepted", "commandId": commandId})
+}
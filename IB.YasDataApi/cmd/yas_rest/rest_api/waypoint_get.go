@@ -0,0 +1,36 @@
+package rest_api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+type GetWaypointParams struct {
+	UserToken string `uri:"token" binding:"required,min=6,max=10"`
+	RouteId int32 `uri:"routeId" binding:"required"`
+	WaypointId int32 `uri:"waypointId" binding:"required"`
+}
+
+func (rest *Rest) GetWaypoint (context *gin.Context) {
+
+		var params GetWaypointParams
+		if err := context.ShouldBindUri(&params); err != nil {
+			log.Error().Err(err).Msg("Wrong URL params")
+			context.JSON(http.StatusBadRequest, gin.H{"msg": "Wrong URL params", "error": err.Error()})
+			return
+		}
+
+		waypoint, err := rest.DataLayer.QueryWaypoint(context.Request.Context(), params.UserToken, params.RouteId, params.WaypointId)
+		if respondNotFound(context, err) {
+			return
+		}
+		if err != nil {
+			log.Error().Err(err).Msg("Unable to get waypoint")
+			context.JSON(http.StatusBadRequest, gin.H{"msg": "Unable to get waypoint", "error": err.Error()})
+			return
+		}
+
+		context.JSON(http.StatusOK, waypoint)
+}
@@ -0,0 +1,57 @@
+package rest_api
+
+import (
+	"net/http"
+
+	"IB.YasDataApi/cmd/yas_rest/kafka"
+	"IB.YasDataApi/abstract/command"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+type MoveWaypointParams struct {
+	UserToken string `uri:"token" binding:"required,min=7,max=11"`
+	RouteId int32 `uri:"routeId" binding:"required"`
+	WaypointId int32 `uri:"waypointId" binding:"required"`
+}
+
+type MoveWaypointBody struct {
+	Lat *float64 `json:"lat" binding:"required,min=-90,max=90"`
+	Lon *float64 `json:"lon" binding:"required,min=-180,max=180"`
+}
+
+func (rest *Rest) MoveWaypoint (context *gin.Context) {
+
+		var params MoveWaypointParams
+		if err := context.ShouldBindUri(&params); err != nil {
+			log.Error().Err(err).Msg("Wrong URL params")
+			context.JSON(http.StatusBadRequest, gin.H{"msg": "Wrong URL params", "error": err.Error()})
+			return
+		}
+
+		var body MoveWaypointBody
+		if err := context.ShouldBindJSON(&body); err != nil {
+			log.Error().Err(err).Msg("Wrong JSON params")
+			context.JSON(http.StatusBadRequest, gin.H{"msg": "Wrong JSON params", "error": err.Error()})
+			return
+		}
+
+		commandId, err := kafka.SendCommand (
+			context.Request.Context(),
+			rest.Producer,
+			command.CmdMoveWaypoint,
+			command.MoveWaypoint {
+				Token: params.UserToken,
+				RouteId: params.RouteId,
+				WaypointId: params.WaypointId,
+				Lat: *body.Lat,
+				Lon: *body.Lon,
+		})
+		if err != nil {
+			context.JSON(http.StatusServiceUnavailable, gin.H{"msg": "Unable to move the waypoint", "error": err.Error()})
+			return
+		}
+
+		context.Header("Location", CommandLocation(commandId))
+		context.JSON(http.StatusAccepted, gin.H{"msg": "The waypoint move has been accepted", "commandId": commandId})
+}
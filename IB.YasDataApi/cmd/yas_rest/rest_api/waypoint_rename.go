@@ -0,0 +1,55 @@
+package rest_api
+
+import (
+	"net/http"
+
+	"IB.YasDataApi/cmd/yas_rest/kafka"
+	"IB.YasDataApi/abstract/command"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+type RenameWaypointParams struct {
+	UserToken string `uri:"token" binding:"required,min=7,max=11"`
+	RouteId int32 `uri:"routeId" binding:"required"`
+	WaypointId int32 `uri:"waypointId" binding:"required"`
+}
+
+type RenameWaypointBody struct {
+	WaypointName string `json:"waypointName" binding:"max=256"`
+}
+
+func (rest *Rest) RenameWaypoint (context *gin.Context) {
+
+		var params RenameWaypointParams
+		if err := context.ShouldBindUri(&params); err != nil {
+			log.Error().Err(err).Msg("Wrong URL params")
+			context.JSON(http.StatusBadRequest, gin.H{"msg": "Wrong URL params", "error": err.Error()})
+			return
+		}
+
+		var body RenameWaypointBody
+		if err := context.ShouldBindJSON(&body); err != nil {
+			log.Error().Err(err).Msg("Wrong JSON params")
+			context.JSON(http.StatusBadRequest, gin.H{"msg": "Wrong JSON params", "error": err.Error()})
+			return
+		}
+
+		commandId, err := kafka.SendCommand (
+			context.Request.Context(),
+			rest.Producer,
+			command.CmdRenameWaypoint,
+			command.RenameWaypoint {
+				Token: params.UserToken,
+				RouteId: params.RouteId,
+				WaypointId: params.WaypointId,
+				WaypointName: body.WaypointName,
+		})
+		if err != nil {
+			context.JSON(http.StatusServiceUnavailable, gin.H{"msg": "Unable to rename the waypoint", "error": err.Error()})
+			return
+		}
+
+		context.Header("Location", CommandLocation(commandId))
+		context.JSON(http.StatusAccepted, gin.H{"msg": "The waypoint rename has been accepted", "commandId": commandId})
+}
@@ -0,0 +1,56 @@
+package rest_api
+
+import (
+	"net/http"
+
+	"IB.YasDataApi/cmd/yas_rest/kafka"
+	"IB.YasDataApi/abstract/command"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+type ReorderWaypointsParams struct {
+	UserToken string `uri:"token" binding:"required,min=7,max=11"`
+	RouteId int32 `uri:"routeId" binding:"required"`
+}
+
+// The new order of the route is the order of the ids, every waypoint of the route must be listed once.
+// The check against the stored waypoints is done by the processor
+//
+type ReorderWaypointsBody struct {
+	WaypointIds []int32 `json:"waypointIds" binding:"required,min=1,max=5000,unique"`
+}
+
+func (rest *Rest) ReorderWaypoints (context *gin.Context) {
+
+		var params ReorderWaypointsParams
+		if err := context.ShouldBindUri(&params); err != nil {
+			log.Error().Err(err).Msg("Wrong URL params")
+			context.JSON(http.StatusBadRequest, gin.H{"msg": "Wrong URL params", "error": err.Error()})
+			return
+		}
+
+		var body ReorderWaypointsBody
+		if err := context.ShouldBindJSON(&body); err != nil {
+			log.Error().Err(err).Msg("Wrong JSON params")
+			context.JSON(http.StatusBadRequest, gin.H{"msg": "Wrong JSON params", "error": err.Error()})
+			return
+		}
+
+		commandId, err := kafka.SendCommand (
+			context.Request.Context(),
+			rest.Producer,
+			command.CmdReorderWaypoints,
+			command.ReorderWaypoints {
+				Token: params.UserToken,
+				RouteId: params.RouteId,
+				WaypointIds: body.WaypointIds,
+		})
+		if err != nil {
+			context.JSON(http.StatusServiceUnavailable, gin.H{"msg": "Unable to reorder the waypoints", "error": err.Error()})
+			return
+		}
+
+		context.Header("Location", CommandLocation(commandId))
+		context.JSON(http.StatusAccepted, gin.H{"msg": "The waypoint order has been accepted", "commandId": commandId})
+}
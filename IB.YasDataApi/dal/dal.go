@@ -2,31 +2,125 @@ package dal
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
 
+	"github.com/jackc/pgconn"
 	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
 	"github.com/rs/zerolog/log"
+	"github.com/segmentio/ksuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.9.0"
+	"go.opentelemetry.io/otel/trace"
 
 	"IB.YasDataApi/abstract"
 	"IB.YasDataApi/abstract/command"
 	"IB.YasDataApi/dal/yasdb"
 )
 
+// Returned when the command with the same id has already been processed
+//
+var ErrDuplicateCommand = errors.New("command has already been processed")
+
+// Returned by the single route, waypoint and track queries to tell which part of the path is unknown
+//
+var (
+	ErrUserNotFound = errors.New("user not found")
+	ErrRouteNotFound = errors.New("route not found")
+	ErrWaypointNotFound = errors.New("waypoint not found")
+	ErrTrackNotFound = errors.New("track not found")
+)
+
+// Returned for the command id which is not known to the processor and is older than the command retention
+//
+var ErrCommandNotFound = errors.New("command not found")
+
+// Returned by the reorder command when the waypoint ids are not a permutation of the route waypoints
+//
+var ErrInvalidWaypointOrder = errors.New("waypoint ids do not match the route waypoints")
+
+// Wraps the error of the waypoint transformation of ExecSimplifyRoute
+//
+var ErrRejectedWaypoints = errors.New("waypoints rejected")
+
+// Transformation of the route waypoints in the order of the route
+//
+type ReshapeFunc func(waypoints []abstract.Waypoint) ([]abstract.Waypoint, error)
+
+// Domain event of the command. It is stored with the processed command id in the command
+// transaction, so the event of a command redelivered after the commit can be published again
+//
+type CommandEvent struct {
+	Type string
+	Payload interface{}
+}
+
+var tracer = otel.Tracer("IB.YasDataApi/dal")
+
 type Dal struct {
 	Config abstract.Config
+	Pool *pgxpool.Pool
 }
 
-func New(config abstract.Config) Dal {
+// Creates data access layer with the shared connection pool.
+// Connections are established lazily on first use
+//
+func New(config abstract.Config) (Dal, error) {
+	poolConfig, err := pgxpool.ParseConfig(config.PostgreUrl)
+	if err != nil {
+		return Dal{}, err
+	}
+
+	if config.PgPool.MaxConns > 0 {
+		poolConfig.MaxConns = config.PgPool.MaxConns
+	}
+	if config.PgPool.MinConns > 0 {
+		poolConfig.MinConns = config.PgPool.MinConns
+	}
+	if config.PgPool.MaxConnIdleTime > 0 {
+		poolConfig.MaxConnIdleTime = config.PgPool.MaxConnIdleTime
+	}
+	if config.PgPool.MaxConnLifetime > 0 {
+		poolConfig.MaxConnLifetime = config.PgPool.MaxConnLifetime
+	}
+	if config.PgPool.HealthCheckPeriod > 0 {
+		poolConfig.HealthCheckPeriod = config.PgPool.HealthCheckPeriod
+	}
+	poolConfig.LazyConnect = true
+
+	pool, err := pgxpool.ConnectConfig(context.Background(), poolConfig)
+	if err != nil {
+		return Dal{}, err
+	}
+
 	return Dal {
 		Config: config,
-	}
+		Pool: pool,
+	}, nil
 }
 
-func (dal *Dal) QueryUser(telegramId int64) (abstract.User, error) {
+// Closes all connections in the pool
+//
+func (dal *Dal) Close() {
+	log.Debug().Msg("Close database pool")
+	dal.Pool.Close()
+}
+
+func (dal *Dal) QueryUser(ctx context.Context, telegramId int64) (abstract.User, error) {
 
 	// Get raw routes from DB
 	// 
 	yasUser, err := queryDb(
-		dal.Config,
+		ctx,
+		dal.Pool,
+		"GetUser",
 		func(query *yasdb.Queries, ctx context.Context) (yasdb.YasUser, error) {
 			return query.GetUser(ctx, telegramId)
 		})
@@ -35,121 +129,367 @@ func (dal *Dal) QueryUser(telegramId int64) (abstract.User, error) {
 		return abstract.User{}, err
 	}
 
+	return toUser(yasUser), nil
+}
+
+// Returns user by the public token, pgx.ErrNoRows if there is no such user
+//
+func (dal *Dal) QueryUserByToken(ctx context.Context, token string) (abstract.User, error) {
+	yasUser, err := queryDb(
+		ctx,
+		dal.Pool,
+		"GetUserByToken",
+		func(query *yasdb.Queries, ctx context.Context) (yasdb.YasUser, error) {
+			return query.GetUserByToken(ctx, token)
+		})
+	if err != nil {
+		return abstract.User{}, err
+	}
+
+	return toUser(yasUser), nil
+}
+
+func toUser(yasUser yasdb.YasUser) abstract.User {
 	return abstract.User {
 		UserId: yasUser.UserID,
 		PublicId: yasUser.PublicID,
 		TelegramId: yasUser.TelegramID,
 		UserName: yasUser.UserName,
 		RegisterTime: yasUser.RegisterTime,
-	}, nil
+	}
 }
 
-func (dal *Dal) QueryRoutes(token string, limit int32) ([]abstract.Route, error) {
+// Returns one page of the user routes filtered and ordered by the query.
+// The next page cursor is set when there are more routes than the limit.
+// Returns ErrUserNotFound if the page is empty because the user is unknown
+//
+func (dal *Dal) QueryRoutes(ctx context.Context, token string, routeQuery abstract.RouteQuery) (abstract.RoutePage, error) {
 
-	// Get raw routes from DB
+	// Get raw routes from DB, one route more than the limit tells there is the next page
 	// 
+	rowLimit := int32(math.MaxInt32)
+	if routeQuery.Limit > 0 && routeQuery.Limit < math.MaxInt32 {
+		rowLimit = routeQuery.Limit + 1
+	}
 	yasRoutes, err := queryDb(
-		dal.Config,
+		ctx,
+		dal.Pool,
+		"ListRoutes",
 		func(query *yasdb.Queries, ctx context.Context) ([]yasdb.YasRoute, error) {
-			if limit > 0 {
-				return query.ListRoutesWithLimit(ctx, yasdb.ListRoutesWithLimitParams{ PublicID: token, Limit: limit })
-			} else {
-				return query.ListRoutes(ctx, token)
-			}
-			
+			return listRoutes(query, ctx, token, routeQuery, rowLimit)
 		})
 	if err != nil {
-		return nil, err
+		return abstract.RoutePage{}, err
+	}
+
+	if len(yasRoutes) == 0 {
+		if _, err := dal.QueryUserByToken(ctx, token); err == pgx.ErrNoRows {
+			return abstract.RoutePage{}, ErrUserNotFound
+		} else if err != nil {
+			return abstract.RoutePage{}, err
+		}
+		return abstract.RoutePage{ Routes: []abstract.Route{} }, nil
 	}
 
-	// Get raw waypoints form DB
+	var page abstract.RoutePage
+	if routeQuery.Limit > 0 && len(yasRoutes) > int(routeQuery.Limit) {
+		yasRoutes = yasRoutes[:routeQuery.Limit]
+		page.Next = routeCursor(routeQuery.Sort, yasRoutes[len(yasRoutes) - 1])
+	}
+
+	// Get raw waypoints of the page routes form DB
 	//
+	routeIds := make([]int64, 0, len(yasRoutes))
+	for _, r := range yasRoutes {
+		routeIds = append(routeIds, int64(r.RouteID))
+	}
 	yasWaypoints, err := queryDb(
-		dal.Config,
+		ctx,
+		dal.Pool,
+		"ListRoutesWaypoints",
 		func(query *yasdb.Queries, ctx context.Context) ([]yasdb.YasWaypoint, error) {
-			return query.ListWaypoints(ctx, token)
+			return query.ListRoutesWaypoints(ctx, routeIds)
 		})
 	if err != nil {
-		return nil, err
+		return abstract.RoutePage{}, err
 	}
 
 	// construct list of routes
 	//
-	var routes []abstract.Route
+	waypoints := map[int64][]abstract.Waypoint{}
+	for _, w := range yasWaypoints {
+		waypoints[w.RouteID] = append(waypoints[w.RouteID], toWaypoint(w))
+	}
 	for _, r := range yasRoutes {
-		var waypoints []abstract.Waypoint
-		for _, w := range yasWaypoints {
-			if w.RouteID == int64(r.RouteID) {
-				waypoints = append(waypoints, abstract.Waypoint{
-					WaypointId: w.WaypointID,
-					WaypointName: w.WaypointName,
-					Lat: w.Lat,
-					Lon: w.Lon,
-					OrderId: w.OrderID,
-				})
-			}
-		}
-		routes = append(routes, abstract.Route {
+		page.Routes = append(page.Routes, abstract.Route {
 			RouteId:    r.RouteID,
 			UserId:     r.UserID,
 			RouteName:  r.RouteName,
 			UploadTime: r.UploadTime,
-			Waypoints:  waypoints,
+			Waypoints:  waypoints[int64(r.RouteID)],
 		})
 	}
 
-	return routes, nil
+	return page, nil
 }
 
-func (dal *Dal) ExecAddUser(u command.AddUser) {
-	execDb(
-		dal.Config, 
-		func(query *yasdb.Queries, ctx context.Context) error {
-			return query.CreateUser(ctx, yasdb.CreateUserParams { PublicID: u.Token, TelegramID: u.TelegramId, UserName: u.UserName })
+// Upload time after all routes, the start of the newest first list and the open upper bound
+//
+var maxUploadTime = time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// Runs the list query of the sort order. Filters missing in the route query are replaced
+// by the widest bounds, the missing cursor by the position before the first route
+//
+func listRoutes(query *yasdb.Queries, ctx context.Context, token string, routeQuery abstract.RouteQuery, rowLimit int32) ([]yasdb.YasRoute, error) {
+	namePattern := "%" + likeEscaper.Replace(routeQuery.NameContains) + "%"
+	uploadedTo := routeQuery.UploadedTo
+	if uploadedTo.IsZero() {
+		uploadedTo = maxUploadTime
+	}
+	maxWaypoints := int64(math.MaxInt64)
+	if routeQuery.MaxWaypoints > 0 {
+		maxWaypoints = int64(routeQuery.MaxWaypoints)
+	}
+	cursor := routeQuery.After
+
+	switch routeQuery.Sort {
+		case abstract.RouteSortName:
+			params := yasdb.ListRoutesByNameParams {
+				PublicID: token,
+				NamePattern: namePattern,
+				UploadedFrom: routeQuery.UploadedFrom,
+				UploadedTo: uploadedTo,
+				MinWaypoints: int64(routeQuery.MinWaypoints),
+				MaxWaypoints: maxWaypoints,
+				RowLimit: rowLimit,
+			}
+			if cursor != nil {
+				params.CursorName, params.CursorID = cursor.RouteName, cursor.RouteId
+			}
+			return query.ListRoutesByName(ctx, params)
+
+		case abstract.RouteSortOldest:
+			params := yasdb.ListRoutesOldestParams {
+				PublicID: token,
+				NamePattern: namePattern,
+				UploadedFrom: routeQuery.UploadedFrom,
+				UploadedTo: uploadedTo,
+				MinWaypoints: int64(routeQuery.MinWaypoints),
+				MaxWaypoints: maxWaypoints,
+				RowLimit: rowLimit,
+			}
+			if cursor != nil {
+				params.CursorTime, params.CursorID = cursor.UploadTime, cursor.RouteId
+			}
+			return query.ListRoutesOldest(ctx, params)
+
+		default:
+			params := yasdb.ListRoutesNewestParams {
+				PublicID: token,
+				NamePattern: namePattern,
+				UploadedFrom: routeQuery.UploadedFrom,
+				UploadedTo: uploadedTo,
+				MinWaypoints: int64(routeQuery.MinWaypoints),
+				MaxWaypoints: maxWaypoints,
+				CursorTime: maxUploadTime,
+				CursorID: math.MaxInt32,
+				RowLimit: rowLimit,
+			}
+			if cursor != nil {
+				params.CursorTime, params.CursorID = cursor.UploadTime, cursor.RouteId
+			}
+			return query.ListRoutesNewest(ctx, params)
+	}
+}
+
+// Returns the cursor after the route in the sort order
+//
+func routeCursor(sort string, last yasdb.YasRoute) *abstract.RouteCursor {
+	cursor := abstract.RouteCursor{ Sort: sort, RouteId: last.RouteID }
+	if sort == abstract.RouteSortName {
+		cursor.RouteName = last.RouteName
+	} else {
+		cursor.UploadTime = last.UploadTime
+	}
+	return &cursor
+}
+
+// Returns the route of the user with its waypoints.
+// Returns ErrUserNotFound if there is no user with the token and ErrRouteNotFound if the user has no such route
+//
+func (dal *Dal) QueryRoute(ctx context.Context, token string, routeId int32) (abstract.Route, error) {
+	yasRoute, err := dal.queryUserRoute(ctx, token, routeId)
+	if err != nil {
+		return abstract.Route{}, err
+	}
+
+	yasWaypoints, err := queryDb(
+		ctx,
+		dal.Pool,
+		"ListRouteWaypoints",
+		func(query *yasdb.Queries, ctx context.Context) ([]yasdb.YasWaypoint, error) {
+			return query.ListRouteWaypoints(ctx, int64(routeId))
+		})
+	if err != nil {
+		return abstract.Route{}, err
+	}
+
+	var waypoints []abstract.Waypoint
+	for _, w := range yasWaypoints {
+		waypoints = append(waypoints, toWaypoint(w))
+	}
+	return abstract.Route {
+		RouteId:    yasRoute.RouteID,
+		UserId:     yasRoute.UserID,
+		RouteName:  yasRoute.RouteName,
+		UploadTime: yasRoute.UploadTime,
+		Waypoints:  waypoints,
+	}, nil
+}
+
+// Returns the waypoint of the user route.
+// Returns ErrUserNotFound, ErrRouteNotFound or ErrWaypointNotFound for the unknown part of the path
+//
+func (dal *Dal) QueryWaypoint(ctx context.Context, token string, routeId int32, waypointId int32) (abstract.Waypoint, error) {
+	if _, err := dal.queryUserRoute(ctx, token, routeId); err != nil {
+		return abstract.Waypoint{}, err
+	}
+
+	yasWaypoint, err := queryDb(
+		ctx,
+		dal.Pool,
+		"GetWaypoint",
+		func(query *yasdb.Queries, ctx context.Context) (yasdb.YasWaypoint, error) {
+			return query.GetWaypoint(ctx, yasdb.GetWaypointParams{ RouteID: int64(routeId), WaypointID: waypointId })
 		})
+	if err == pgx.ErrNoRows {
+		return abstract.Waypoint{}, ErrWaypointNotFound
+	}
+	if err != nil {
+		return abstract.Waypoint{}, err
+	}
+	return toWaypoint(yasWaypoint), nil
 }
 
-func (dal *Dal) ExecAddRoute(r command.AddRoute) (int32, error) {
-	return queryDb(
-		dal.Config,
-		func(query *yasdb.Queries, ctx context.Context) (int32, error) {
-			return query.AddRoute(ctx, yasdb.AddRouteParams { UserID: r.UserId, RouteName: r.RouteName })
+// Returns the route of the user, ErrUserNotFound or ErrRouteNotFound
+//
+func (dal *Dal) queryUserRoute(ctx context.Context, token string, routeId int32) (yasdb.YasRoute, error) {
+	yasRoute, err := queryDb(
+		ctx,
+		dal.Pool,
+		"GetRoute",
+		func(query *yasdb.Queries, ctx context.Context) (yasdb.YasRoute, error) {
+			return query.GetRoute(ctx, yasdb.GetRouteParams{ PublicID: token, RouteID: routeId })
 		})
+	if err != pgx.ErrNoRows {
+		return yasRoute, err
+	}
+
+	if _, err := dal.QueryUserByToken(ctx, token); err == pgx.ErrNoRows {
+		return yasdb.YasRoute{}, ErrUserNotFound
+	} else if err != nil {
+		return yasdb.YasRoute{}, err
+	}
+	return yasdb.YasRoute{}, ErrRouteNotFound
+}
+
+func toWaypoint(w yasdb.YasWaypoint) abstract.Waypoint {
+	return abstract.Waypoint {
+		WaypointId: w.WaypointID,
+		WaypointName: w.WaypointName,
+		Lat: w.Lat,
+		Lon: w.Lon,
+		OrderId: w.OrderID,
+	}
 }
 
-func (dal *Dal) ExecAddWaypoint(routeId int32, orderId int32, wp command.AddWaypoint) {
-	execDb(
-		dal.Config,
+func (dal *Dal) ExecAddUser(ctx context.Context, commandId string, u command.AddUser, event CommandEvent) error {
+	return execCommand(
+		ctx,
+		dal.Pool,
+		"ExecAddUser",
+		commandId,
+		command.CmdCreateUser,
 		func(query *yasdb.Queries, ctx context.Context) error {
-			return query.AddWaypoint(ctx, yasdb.AddWaypointParams {
-				RouteID: int64(routeId), 
-				WaypointName: wp.WaypointName,
-				Lat: wp.Lat,
-				Lon: wp.Lon,
-				OrderID: orderId,
-			})
-		})
+			return query.CreateUser(ctx, yasdb.CreateUserParams { PublicID: u.Token, TelegramID: u.TelegramId, UserName: u.UserName })
+		},
+		func() CommandEvent { return event })
+}
+
+// Adds route with all its waypoints in a single transaction.
+// Waypoints are bulk-inserted with COPY, order follows the command
+//
+func (dal *Dal) ExecAddRoute(ctx context.Context, commandId string, r command.AddRoute, event func(routeId int32) CommandEvent) (int32, error) {
+	var routeId int32
+	err := execCommand(
+		ctx,
+		dal.Pool,
+		"ExecAddRoute",
+		commandId,
+		command.CmdAddRoute,
+		func(query *yasdb.Queries, ctx context.Context) error {
+			var err error
+			routeId, err = query.AddRoute(ctx, yasdb.AddRouteParams { UserID: r.UserId, RouteName: r.RouteName })
+			if err != nil {
+				return err
+			}
+
+			waypoints := make([]yasdb.AddWaypointsParams, 0, len(r.Waypoints))
+			for id, wp := range r.Waypoints {
+				waypoints = append(waypoints, yasdb.AddWaypointsParams {
+					RouteID: int64(routeId),
+					WaypointName: wp.WaypointName,
+					Lat: wp.Lat,
+					Lon: wp.Lon,
+					OrderID: int32(id),
+				})
+			}
+			_, err = query.AddWaypoints(ctx, waypoints)
+			return err
+		},
+		func() CommandEvent { return event(routeId) })
+	if err != nil {
+		return 0, err
+	}
+
+	return routeId, nil
 }
 
-func (dal *Dal) ExecDeleteRoute(delParams command.DeleteRoute) {
-	execDb(
-		dal.Config,
+func (dal *Dal) ExecDeleteRoute(ctx context.Context, commandId string, delParams command.DeleteRoute, event CommandEvent) error {
+	return execCommand(
+		ctx,
+		dal.Pool,
+		"ExecDeleteRoute",
+		commandId,
+		command.CmdDeleteRoute,
 		func(query *yasdb.Queries, ctx context.Context) error {
 			return query.DeleteRoute(ctx, yasdb.DeleteRouteParams{PublicID: delParams.Token, RouteID: delParams.RouteId })
-		})
+		},
+		func() CommandEvent { return event })
 }
 
-func (dal *Dal) ExecRenameRouteById(routeId int32, userId int64, newName string) {
-	execDb(
-		dal.Config,
+func (dal *Dal) ExecRenameRouteById(ctx context.Context, commandId string, routeId int32, userId int64, newName string, event CommandEvent) error {
+	return execCommand(
+		ctx,
+		dal.Pool,
+		"ExecRenameRouteById",
+		commandId,
+		command.CmdRenameRouteById,
 		func(query *yasdb.Queries, ctx context.Context) error {
 			return query.RenameRouteById(ctx, yasdb.RenameRouteByIdParams{UserID: userId, RouteID: routeId, RouteName: newName })
-		})
+		},
+		func() CommandEvent { return event })
 }
 
-func (dal *Dal) ExecRenameRouteByToken(renameParams command.RenameRouteByToken) {
-	execDb(
-		dal.Config,
+func (dal *Dal) ExecRenameRouteByToken(ctx context.Context, commandId string, renameParams command.RenameRouteByToken, event CommandEvent) error {
+	return execCommand(
+		ctx,
+		dal.Pool,
+		"ExecRenameRouteByToken",
+		commandId,
+		command.CmdRenameRouteByToken,
 		func(query *yasdb.Queries, ctx context.Context) error {
 			return query.RenameRouteByToken(ctx, 
 				yasdb.RenameRouteByTokenParams{
@@ -158,27 +498,346 @@ func (dal *Dal) ExecRenameRouteByToken(renameParams command.RenameRouteByToken)
 					RouteName: renameParams.RouteName, 
 				},
 			)
-		})
+		},
+		func() CommandEvent { return event })
 }
 
-type yasType interface {
-	[]yasdb.YasRoute | []yasdb.YasWaypoint | yasdb.YasUser | int32
+// Inserts the waypoint at the position and shifts the following waypoints,
+// returns the stored waypoint with its id and order
+//
+func (dal *Dal) ExecAddWaypoint(ctx context.Context, commandId string, w command.AddRouteWaypoint, event func(waypoint abstract.Waypoint) CommandEvent) (abstract.Waypoint, error) {
+	waypoint := abstract.Waypoint {
+		WaypointName: w.WaypointName,
+		Lat: w.Lat,
+		Lon: w.Lon,
+	}
+	err := execCommand(
+		ctx,
+		dal.Pool,
+		"ExecAddWaypoint",
+		commandId,
+		command.CmdAddWaypoint,
+		func(query *yasdb.Queries, ctx context.Context) error {
+			routeId, err := lockRoute(query, ctx, w.Token, w.RouteId)
+			if err != nil {
+				return err
+			}
+			if err := query.RenumberWaypoints(ctx, routeId); err != nil {
+				return err
+			}
+
+			count, err := query.CountRouteWaypoints(ctx, routeId)
+			if err != nil {
+				return err
+			}
+			waypoint.OrderId = int32(count)
+			if w.Position != nil && *w.Position >= 0 && int64(*w.Position) < count {
+				waypoint.OrderId = *w.Position
+			}
+			if err := query.ShiftWaypoints(ctx, yasdb.ShiftWaypointsParams{ RouteID: routeId, OrderID: waypoint.OrderId }); err != nil {
+				return err
+			}
+
+			waypoint.WaypointId, err = query.InsertWaypoint(ctx, yasdb.InsertWaypointParams {
+				RouteID: routeId,
+				WaypointName: waypoint.WaypointName,
+				Lat: waypoint.Lat,
+				Lon: waypoint.Lon,
+				OrderID: waypoint.OrderId,
+			})
+			return err
+		},
+		func() CommandEvent { return event(waypoint) })
+	return waypoint, err
 }
 
-type queryFunc[T yasType] func(query *yasdb.Queries, ctx context.Context) (T, error)
+func (dal *Dal) ExecMoveWaypoint(ctx context.Context, commandId string, w command.MoveWaypoint, event CommandEvent) error {
+	return execCommand(
+		ctx,
+		dal.Pool,
+		"ExecMoveWaypoint",
+		commandId,
+		command.CmdMoveWaypoint,
+		func(query *yasdb.Queries, ctx context.Context) error {
+			routeId, err := lockRoute(query, ctx, w.Token, w.RouteId)
+			if err != nil {
+				return err
+			}
+			return waypointRows(query.MoveWaypoint(ctx, yasdb.MoveWaypointParams {
+				RouteID: routeId,
+				WaypointID: w.WaypointId,
+				Lat: w.Lat,
+				Lon: w.Lon,
+			}))
+		},
+		func() CommandEvent { return event })
+}
+
+func (dal *Dal) ExecRenameWaypoint(ctx context.Context, commandId string, w command.RenameWaypoint, event CommandEvent) error {
+	return execCommand(
+		ctx,
+		dal.Pool,
+		"ExecRenameWaypoint",
+		commandId,
+		command.CmdRenameWaypoint,
+		func(query *yasdb.Queries, ctx context.Context) error {
+			routeId, err := lockRoute(query, ctx, w.Token, w.RouteId)
+			if err != nil {
+				return err
+			}
+			return waypointRows(query.RenameWaypoint(ctx, yasdb.RenameWaypointParams {
+				RouteID: routeId,
+				WaypointID: w.WaypointId,
+				WaypointName: w.WaypointName,
+			}))
+		},
+		func() CommandEvent { return event })
+}
+
+// Deletes the waypoint and closes the gap in the order of the rest
+//
+func (dal *Dal) ExecDeleteWaypoint(ctx context.Context, commandId string, w command.DeleteWaypoint, event CommandEvent) error {
+	return execCommand(
+		ctx,
+		dal.Pool,
+		"ExecDeleteWaypoint",
+		commandId,
+		command.CmdDeleteWaypoint,
+		func(query *yasdb.Queries, ctx context.Context) error {
+			routeId, err := lockRoute(query, ctx, w.Token, w.RouteId)
+			if err != nil {
+				return err
+			}
+			err = waypointRows(query.DeleteWaypoint(ctx, yasdb.DeleteWaypointParams{ RouteID: routeId, WaypointID: w.WaypointId }))
+			if err != nil {
+				return err
+			}
+			return query.RenumberWaypoints(ctx, routeId)
+		},
+		func() CommandEvent { return event })
+}
+
+// Sets the order of the waypoints to the order of the ids.
+// Returns ErrInvalidWaypointOrder unless every waypoint of the route is listed exactly once
+//
+func (dal *Dal) ExecReorderWaypoints(ctx context.Context, commandId string, w command.ReorderWaypoints, event CommandEvent) error {
+	return execCommand(
+		ctx,
+		dal.Pool,
+		"ExecReorderWaypoints",
+		commandId,
+		command.CmdReorderWaypoints,
+		func(query *yasdb.Queries, ctx context.Context) error {
+			routeId, err := lockRoute(query, ctx, w.Token, w.RouteId)
+			if err != nil {
+				return err
+			}
+			yasWaypoints, err := query.ListRouteWaypoints(ctx, routeId)
+			if err != nil {
+				return err
+			}
+
+			listed := map[int32]bool{}
+			for _, id := range w.WaypointIds {
+				listed[id] = true
+			}
+			if len(listed) != len(w.WaypointIds) || len(listed) != len(yasWaypoints) {
+				return ErrInvalidWaypointOrder
+			}
+			for _, yasWaypoint := range yasWaypoints {
+				if !listed[yasWaypoint.WaypointID] {
+					return ErrInvalidWaypointOrder
+				}
+			}
+
+			_, err = query.ReorderWaypoints(ctx, yasdb.ReorderWaypointsParams{ WaypointIds: w.WaypointIds, RouteID: routeId })
+			return err
+		},
+		func() CommandEvent { return event })
+}
+
+// Replaces the waypoints of the user route with the reshaped ones, returns the number of waypoints.
+// The waypoints get new ids
+//
+func (dal *Dal) ExecSimplifyRoute(ctx context.Context, commandId string, token string, routeId int32, reshape ReshapeFunc, event func(waypoints int) CommandEvent) (int, error) {
+	var count int
+	err := execCommand(
+		ctx,
+		dal.Pool,
+		"ExecSimplifyRoute",
+		commandId,
+		command.CmdSimplifyRoute,
+		func(query *yasdb.Queries, ctx context.Context) error {
+			lockedId, err := lockRoute(query, ctx, token, routeId)
+			if err != nil {
+				return err
+			}
+			yasWaypoints, err := query.ListRouteWaypoints(ctx, lockedId)
+			if err != nil {
+				return err
+			}
+			stored := make([]abstract.Waypoint, 0, len(yasWaypoints))
+			for _, w := range yasWaypoints {
+				stored = append(stored, toWaypoint(w))
+			}
+
+			reshaped, err := reshape(stored)
+			if err != nil {
+				return fmt.Errorf("%w: %v", ErrRejectedWaypoints, err)
+			}
+			count = len(reshaped)
+
+			if err := query.DeleteRouteWaypoints(ctx, lockedId); err != nil {
+				return err
+			}
+			waypoints := make([]yasdb.AddWaypointsParams, 0, len(reshaped))
+			for id, wp := range reshaped {
+				waypoints = append(waypoints, yasdb.AddWaypointsParams {
+					RouteID: lockedId,
+					WaypointName: wp.WaypointName,
+					Lat: wp.Lat,
+					Lon: wp.Lon,
+					OrderID: int32(id),
+				})
+			}
+			_, err = query.AddWaypoints(ctx, waypoints)
+			return err
+		},
+		func() CommandEvent { return event(count) })
+	return count, err
+}
+
+// Locks the user route for the waypoint edit until the end of the transaction, so concurrent
+// edits of the same route keep the order contiguous. Returns ErrUserNotFound or ErrRouteNotFound
+//
+func lockRoute(query *yasdb.Queries, ctx context.Context, token string, routeId int32) (int64, error) {
+	lockedId, err := query.LockRoute(ctx, yasdb.LockRouteParams{ PublicID: token, RouteID: routeId })
+	if err == nil {
+		return int64(lockedId), nil
+	}
+	if err != pgx.ErrNoRows {
+		return 0, err
+	}
+
+	if _, err := query.GetUserByToken(ctx, token); err == pgx.ErrNoRows {
+		return 0, ErrUserNotFound
+	} else if err != nil {
+		return 0, err
+	}
+	return 0, ErrRouteNotFound
+}
+
+// Converts the number of updated waypoints into ErrWaypointNotFound
+//
+func waypointRows(rows int64, err error) error {
+	if err == nil && rows == 0 {
+		return ErrWaypointNotFound
+	}
+	return err
+}
 
-func queryDb[T yasType](config abstract.Config, query queryFunc[T]) (T, error) {
-	ctx := context.Background()
-	conn, err := pgx.Connect(ctx, config.PostgreUrl)
+// Returns processing status of the command. Commands unknown to the processor are pending while
+// the command id, a KSUID, is younger than the command retention: the command is in flight or has not
+// been sent at all. Older unknown commands and ids which are not KSUIDs return ErrCommandNotFound,
+// their status has been pruned or never existed
+//
+func (dal *Dal) QueryCommandStatus(ctx context.Context, commandId string) (abstract.CommandStatus, error) {
+	yasCommand, err := queryDb(
+		ctx,
+		dal.Pool,
+		"GetCommand",
+		func(query *yasdb.Queries, ctx context.Context) (yasdb.YasProcessedCommand, error) {
+			return query.GetCommand(ctx, commandId)
+		})
+	if errors.Is(err, pgx.ErrNoRows) {
+		id, err := ksuid.Parse(commandId)
+		if err != nil || time.Since(id.Time()) > dal.Config.Processor.CommandRetentionOrDefault() {
+			return abstract.CommandStatus{}, ErrCommandNotFound
+		}
+		return abstract.CommandStatus { CommandId: commandId, Status: abstract.CommandPending }, nil
+	}
 	if err != nil {
-		var empty T
-		return empty, err
+		return abstract.CommandStatus{}, err
+	}
+
+	return abstract.CommandStatus {
+		CommandId: yasCommand.CommandID,
+		Command: yasCommand.Command,
+		Status: yasCommand.Status,
+		Error: yasCommand.ErrorMessage,
+		ProcessedTime: &yasCommand.ProcessedTime,
+	}, nil
+}
+
+// Returns the event stored with the applied command, empty event if the command has
+// no event or its id has been pruned
+//
+func (dal *Dal) QueryCommandEvent(ctx context.Context, commandId string) (CommandEvent, error) {
+	yasCommand, err := queryDb(
+		ctx,
+		dal.Pool,
+		"GetCommand",
+		func(query *yasdb.Queries, ctx context.Context) (yasdb.YasProcessedCommand, error) {
+			return query.GetCommand(ctx, commandId)
+		})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return CommandEvent{}, nil
+	}
+	if err != nil {
+		return CommandEvent{}, err
+	}
+	if yasCommand.EventType == "" {
+		return CommandEvent{}, nil
 	}
-	defer conn.Close(ctx)
 
-	queries := yasdb.New(conn)
+	return CommandEvent {
+		Type: yasCommand.EventType,
+		Payload: json.RawMessage(yasCommand.EventPayload),
+	}, nil
+}
+
+// Records the command as failed with the reason, unless it has already been applied
+//
+func (dal *Dal) ExecCommandFailed(ctx context.Context, commandId string, commandName string, reason string) error {
+	return execTx(
+		ctx,
+		dal.Pool,
+		"ExecCommandFailed",
+		func(query *yasdb.Queries, ctx context.Context) error {
+			return query.AddFailedCommand(ctx, yasdb.AddFailedCommandParams {
+				CommandID: commandId,
+				Command: commandName,
+				ErrorMessage: reason,
+			})
+		})
+}
+
+// Removes ids of commands processed before the given time.
+// Returns number of removed ids
+//
+func (dal *Dal) PruneProcessedCommands(ctx context.Context, before time.Time) (int64, error) {
+	return queryDb(
+		ctx,
+		dal.Pool,
+		"DeleteProcessedCommands",
+		func(query *yasdb.Queries, ctx context.Context) (int64, error) {
+			return query.DeleteProcessedCommands(ctx, before)
+		})
+}
+
+type yasType interface {
+	[]yasdb.YasRoute | []yasdb.YasWaypoint | yasdb.YasRoute | yasdb.YasWaypoint | yasdb.YasUser | yasdb.YasProcessedCommand | int32 | int64 |
+		[]yasdb.YasTrack | yasdb.YasTrack | []yasdb.YasTrackPoint
+}
+
+type queryFunc[T yasType] func(query *yasdb.Queries, ctx context.Context) (T, error)
+
+func queryDb[T yasType](ctx context.Context, pool *pgxpool.Pool, name string, query queryFunc[T]) (T, error) {
+	ctx, span := startSpan(ctx, name)
+	queries := yasdb.New(pool)
 
 	result, err := query(queries, ctx)
+	endSpan(span, err)
 	if err != nil {
 		var empty T
 		return empty, err
@@ -190,22 +849,99 @@ func queryDb[T yasType](config abstract.Config, query queryFunc[T]) (T, error) {
 
 type execFunc func(query *yasdb.Queries, ctx context.Context) error
 
-// Execute query without result. Errors are not porpagated
+// Execute queries in a single transaction. The transaction is rolled back if exec returns error
 //
-func execDb(config abstract.Config, exec execFunc) {
-	ctx := context.Background()
-	conn, err := pgx.Connect(ctx, config.PostgreUrl)
-	if err != nil {
-		log.Error().Err(err).Msg("Cannot establish connection to postgres")
-		return 
+func execTx(ctx context.Context, pool *pgxpool.Pool, name string, exec execFunc) error {
+	ctx, span := startSpan(ctx, name)
+	err := pool.BeginFunc(ctx, func(tx pgx.Tx) error {
+		return exec(yasdb.New(pool).WithTx(tx), ctx)
+	})
+	endSpan(span, err)
+	return err
+}
+
+// Execute queries of the command in a single transaction together with recording of the command id
+// and the event of the command, which is built after exec succeeds.
+// If the command id has already been recorded the transaction is rolled back and ErrDuplicateCommand returned
+//
+func execCommand(ctx context.Context, pool *pgxpool.Pool, name string, commandId string, commandName string, exec execFunc, event func() CommandEvent) error {
+	if commandId == "" {
+		return execTx(ctx, pool, name, exec)
 	}
-	defer conn.Close(ctx)
 
-	queries := yasdb.New(conn)
+	return execTx(ctx, pool, name, func(query *yasdb.Queries, ctx context.Context) error {
+		rows, err := query.AddProcessedCommand(ctx, yasdb.AddProcessedCommandParams{ CommandID: commandId, Command: commandName })
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return ErrDuplicateCommand
+		}
+		if err := exec(query, ctx); err != nil {
+			return err
+		}
 
-	err = exec(queries, ctx)
-	if err != nil {
-		log.Error().Err(err).Msg("Error executing query")
-		return 
+		evt := event()
+		if evt.Type == "" {
+			return nil
+		}
+		payload, err := json.Marshal(evt.Payload)
+		if err != nil {
+			return err
+		}
+		return query.SetCommandEvent(ctx, yasdb.SetCommandEventParams {
+			CommandID: commandId,
+			EventType: evt.Type,
+			EventPayload: string(payload),
+		})
+	})
+}
+
+// Starts span of the database call as a child of the span in ctx
+//
+func startSpan(ctx context.Context, name string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "dal/" + name, 
+		trace.WithSpanKind(trace.SpanKindClient), 
+		trace.WithAttributes(semconv.DBSystemPostgreSQL))
+}
+
+// Records error of the database call and ends the span. Duplicate commands and missing rows are not errors
+//
+func endSpan(span trace.Span, err error) {
+	if errors.Is(err, ErrDuplicateCommand) {
+		span.SetAttributes(attribute.Bool("duplicate", true))
+	} else if errors.Is(err, pgx.ErrNoRows) {
+		span.SetAttributes(attribute.Bool("found", false))
+	} else if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Reports whether the error is worth to retry: lost connection, serialization failure,
+// lack of resources or server shutdown. Constraint violations, wrong data and other
+// errors reported by postgres are permanent
+//
+func IsTransientError(err error) bool {
+	if errors.Is(err, pgx.ErrNoRows) || errors.Is(err, ErrDuplicateCommand) {
+		return false
+	}
+	if errors.Is(err, ErrUserNotFound) || errors.Is(err, ErrRouteNotFound) ||
+		errors.Is(err, ErrWaypointNotFound) || errors.Is(err, ErrInvalidWaypointOrder) ||
+		errors.Is(err, ErrRejectedWaypoints) || errors.Is(err, ErrTrackNotFound) {
+		return false
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code[:2] {
+			case "08", "40", "53", "57":
+				return true
+			default:
+				return false
+		}
 	}
-}
\ No newline at end of file
+
+	return true
+}
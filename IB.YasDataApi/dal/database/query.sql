@@ -1,26 +1,61 @@
--- name: ListRoutes :many
+-- name: ListRoutesNewest :many
 SELECT r.* FROM yas_route r
-JOIN yas_user u ON r.user_id = u.user_id 
-WHERE  u.public_id = $1
-ORDER BY upload_time DESC;
+JOIN yas_user u ON r.user_id = u.user_id
+WHERE u.public_id = @public_id
+    AND r.route_name ILIKE @name_pattern
+    AND r.upload_time >= @uploaded_from AND r.upload_time < @uploaded_to
+    AND (SELECT count(*) FROM yas_waypoint wp WHERE wp.route_id = r.route_id) BETWEEN @min_waypoints::bigint AND @max_waypoints::bigint
+    AND (r.upload_time, r.route_id) < (@cursor_time::timestamptz, @cursor_id::integer)
+ORDER BY r.upload_time DESC, r.route_id DESC
+LIMIT @row_limit;
 
--- name: ListRoutesWithLimit :many
+-- name: ListRoutesOldest :many
 SELECT r.* FROM yas_route r
-JOIN yas_user u ON r.user_id = u.user_id 
-WHERE  u.public_id = $1
-ORDER BY upload_time DESC
-LIMIT $2;
-
--- name: ListWaypoints :many
-SELECT wp.waypoint_id, wp.route_id, COALESCE(wp.waypoint_name, '') as waypoint_name, wp.lat, wp.lon, wp.order_id FROM yas_waypoint wp
-JOIN yas_route r ON wp.route_id = r.route_id
 JOIN yas_user u ON r.user_id = u.user_id
-WHERE u.public_id = $1
-ORDER BY wp.order_id ASC, wp.waypoint_id ASC;
+WHERE u.public_id = @public_id
+    AND r.route_name ILIKE @name_pattern
+    AND r.upload_time >= @uploaded_from AND r.upload_time < @uploaded_to
+    AND (SELECT count(*) FROM yas_waypoint wp WHERE wp.route_id = r.route_id) BETWEEN @min_waypoints::bigint AND @max_waypoints::bigint
+    AND (r.upload_time, r.route_id) > (@cursor_time::timestamptz, @cursor_id::integer)
+ORDER BY r.upload_time ASC, r.route_id ASC
+LIMIT @row_limit;
+
+-- name: ListRoutesByName :many
+SELECT r.* FROM yas_route r
+JOIN yas_user u ON r.user_id = u.user_id
+WHERE u.public_id = @public_id
+    AND r.route_name ILIKE @name_pattern
+    AND r.upload_time >= @uploaded_from AND r.upload_time < @uploaded_to
+    AND (SELECT count(*) FROM yas_waypoint wp WHERE wp.route_id = r.route_id) BETWEEN @min_waypoints::bigint AND @max_waypoints::bigint
+    AND (r.route_name, r.route_id) > (@cursor_name::text, @cursor_id::integer)
+ORDER BY r.route_name ASC, r.route_id ASC
+LIMIT @row_limit;
+
+-- name: ListRoutesWaypoints :many
+SELECT waypoint_id, route_id, COALESCE(waypoint_name, '') as waypoint_name, lat, lon, order_id FROM yas_waypoint
+WHERE route_id = ANY(@route_ids::bigint[])
+ORDER BY route_id ASC, order_id ASC, waypoint_id ASC;
+
+-- name: GetRoute :one
+SELECT r.* FROM yas_route r
+JOIN yas_user u ON r.user_id = u.user_id
+WHERE u.public_id = $1 AND r.route_id = $2;
+
+-- name: ListRouteWaypoints :many
+SELECT waypoint_id, route_id, COALESCE(waypoint_name, '') as waypoint_name, lat, lon, order_id FROM yas_waypoint
+WHERE route_id = $1
+ORDER BY order_id ASC, waypoint_id ASC;
+
+-- name: GetWaypoint :one
+SELECT waypoint_id, route_id, COALESCE(waypoint_name, '') as waypoint_name, lat, lon, order_id FROM yas_waypoint
+WHERE route_id = $1 AND waypoint_id = $2;
 
 -- name: GetUser :one
 SELECT user_id, public_id, telegram_id, COALESCE(user_name, '') as user_name, register_time FROM yas_user WHERE telegram_id = $1;
 
+-- name: GetUserByToken :one
+SELECT user_id, public_id, telegram_id, COALESCE(user_name, '') as user_name, register_time FROM yas_user WHERE public_id = $1;
+
 -- name: CreateUser :exec
 INSERT INTO yas_user (public_id, telegram_id, user_name, register_time)
     VALUES ($1, $2, $3, now())
@@ -33,6 +68,9 @@ RETURNING route_id;
 -- name: AddWaypoint :exec
 INSERT INTO yas_waypoint (route_id, waypoint_name, lat, lon, order_id) VALUES ($1, $2, $3, $4, $5);
 
+-- name: AddWaypoints :copyfrom
+INSERT INTO yas_waypoint (route_id, waypoint_name, lat, lon, order_id) VALUES ($1, $2, $3, $4, $5);
+
 -- name: DeleteRoute :exec
 DELETE FROM yas_route WHERE route_id = $1 AND user_id = (SELECT user_id FROM yas_user WHERE public_id = $2);
 
@@ -41,3 +79,99 @@ UPDATE yas_route SET route_name = $3 WHERE route_id = $1 AND user_id = $2;
 
 -- name: RenameRouteByToken :exec
 UPDATE yas_route SET route_name = $3 WHERE route_id = $1 AND user_id = (SELECT user_id FROM yas_user WHERE public_id = $2);
+
+-- name: LockRoute :one
+SELECT r.route_id FROM yas_route r
+JOIN yas_user u ON r.user_id = u.user_id
+WHERE u.public_id = $1 AND r.route_id = $2
+FOR UPDATE OF r;
+
+-- name: CountRouteWaypoints :one
+SELECT count(*) FROM yas_waypoint WHERE route_id = $1;
+
+-- name: ShiftWaypoints :exec
+UPDATE yas_waypoint SET order_id = order_id + 1 WHERE route_id = $1 AND order_id >= $2;
+
+-- name: InsertWaypoint :one
+INSERT INTO yas_waypoint (route_id, waypoint_name, lat, lon, order_id) VALUES ($1, $2, $3, $4, $5)
+RETURNING waypoint_id;
+
+-- name: MoveWaypoint :execrows
+UPDATE yas_waypoint SET lat = $3, lon = $4 WHERE route_id = $1 AND waypoint_id = $2;
+
+-- name: RenameWaypoint :execrows
+UPDATE yas_waypoint SET waypoint_name = $3 WHERE route_id = $1 AND waypoint_id = $2;
+
+-- name: DeleteWaypoint :execrows
+DELETE FROM yas_waypoint WHERE route_id = $1 AND waypoint_id = $2;
+
+-- name: DeleteRouteWaypoints :exec
+DELETE FROM yas_waypoint WHERE route_id = $1;
+
+-- name: RenumberWaypoints :exec
+UPDATE yas_waypoint SET order_id = n.order_id
+FROM (
+    SELECT waypoint_id, (row_number() OVER (ORDER BY order_id, waypoint_id) - 1)::integer AS order_id
+    FROM yas_waypoint WHERE route_id = $1
+) n
+WHERE yas_waypoint.route_id = $1 AND yas_waypoint.waypoint_id = n.waypoint_id AND yas_waypoint.order_id <> n.order_id;
+
+-- name: ReorderWaypoints :execrows
+UPDATE yas_waypoint SET order_id = (o.ordinal - 1)::integer
+FROM unnest(@waypoint_ids::integer[]) WITH ORDINALITY AS o(waypoint_id, ordinal)
+WHERE yas_waypoint.route_id = @route_id AND yas_waypoint.waypoint_id = o.waypoint_id;
+
+-- name: ListTracks :many
+SELECT t.* FROM yas_track t
+JOIN yas_user u ON t.user_id = u.user_id
+WHERE u.public_id = $1
+ORDER BY t.track_id DESC;
+
+-- name: GetTrack :one
+SELECT t.* FROM yas_track t
+JOIN yas_user u ON t.user_id = u.user_id
+WHERE u.public_id = $1 AND t.track_id = $2;
+
+-- name: ListTrackPoints :many
+SELECT * FROM yas_track_point
+WHERE track_id = @track_id AND point_time >= @time_from AND point_time < @time_to
+ORDER BY point_time ASC
+LIMIT @row_limit;
+
+-- name: UpsertTrack :one
+INSERT INTO yas_track (user_id, track_key, track_name, create_time)
+SELECT u.user_id, @track_key::text, @track_name::text, now() FROM yas_user u WHERE u.public_id = @public_id
+ON CONFLICT (user_id, track_key) DO UPDATE
+    SET track_name = CASE WHEN EXCLUDED.track_name = '' THEN yas_track.track_name ELSE EXCLUDED.track_name END
+RETURNING track_id, end_time;
+
+-- name: AddTrackPoints :copyfrom
+INSERT INTO yas_track_point (point_time, track_id, lat_e7, lon_e7, sog_e1, cog_e1, heading_e1) VALUES ($1, $2, $3, $4, $5, $6, $7);
+
+-- name: UpdateTrackSpan :exec
+UPDATE yas_track SET
+    start_time = COALESCE(start_time, @start_time::timestamptz),
+    end_time = @end_time::timestamptz,
+    point_count = point_count + @point_count::integer
+WHERE track_id = @track_id;
+
+-- name: AddProcessedCommand :execrows
+INSERT INTO yas_processed_command (command_id, command, processed_time, status, error_message) 
+    VALUES ($1, $2, now(), 'applied', '')
+ON CONFLICT (command_id) DO UPDATE SET processed_time = now(), status = 'applied', error_message = '', event_type = '', event_payload = ''
+    WHERE yas_processed_command.status <> 'applied';
+
+-- name: SetCommandEvent :exec
+UPDATE yas_processed_command SET event_type = $2, event_payload = $3 WHERE command_id = $1;
+
+-- name: AddFailedCommand :exec
+INSERT INTO yas_processed_command (command_id, command, processed_time, status, error_message) 
+    VALUES ($1, $2, now(), 'failed', $3)
+ON CONFLICT (command_id) DO UPDATE SET processed_time = now(), status = 'failed', error_message = $3
+    WHERE yas_processed_command.status <> 'applied';
+
+-- name: GetCommand :one
+SELECT command_id, command, processed_time, status, error_message, event_type, event_payload FROM yas_processed_command WHERE command_id = $1;
+
+-- name: DeleteProcessedCommands :execrows
+DELETE FROM yas_processed_command WHERE processed_time < $1;
@@ -18,6 +18,7 @@ CREATE TABLE yas_route(
 );
 CREATE UNIQUE INDEX ixu_route_routeid ON "yas_route" USING btree ("route_id");
 CREATE INDEX ixu_route_userid ON "yas_route" USING btree ("user_id");
+CREATE INDEX ix_route_user_upload ON "yas_route" USING btree ("user_id", "upload_time", "route_id");
 
 
 
@@ -31,4 +32,44 @@ CREATE TABLE yas_waypoint(
     order_id integer NOT NULL DEFAULT 0
 );
 CREATE INDEX ix_waypoint_routeid ON "yas_waypoint" USING btree ("route_id");
-CREATE INDEX ixu_waypointid ON "yas_waypoint" USING btree ("waypoint_id");
\ No newline at end of file
+CREATE INDEX ixu_waypointid ON "yas_waypoint" USING btree ("waypoint_id");
+
+
+CREATE TABLE yas_track(
+    track_id SERIAL NOT NULL PRIMARY KEY,
+    user_id bigint NOT NULL,
+    track_key character varying NOT NULL,
+    track_name character varying NOT NULL DEFAULT '',
+    create_time timestamp with time zone NOT NULL DEFAULT now(),
+    start_time timestamp with time zone,
+    end_time timestamp with time zone,
+    point_count integer NOT NULL DEFAULT 0
+);
+CREATE UNIQUE INDEX ixu_track_user_key ON "yas_track" USING btree ("user_id", "track_key");
+
+-- One row per logged point, 1 Hz logging is 86400 rows a day per track. Columns are ordered
+-- without alignment padding, position is fixed point 1e-7 degree, SOG is 0.1 kn,
+-- COG and heading are 0.1 degree and missing values are NULL
+--
+CREATE TABLE yas_track_point(
+    point_time timestamp with time zone NOT NULL,
+    track_id integer NOT NULL,
+    lat_e7 integer NOT NULL,
+    lon_e7 integer NOT NULL,
+    sog_e1 smallint,
+    cog_e1 smallint,
+    heading_e1 smallint,
+    PRIMARY KEY (track_id, point_time)
+);
+
+
+CREATE TABLE yas_processed_command(
+    command_id character varying NOT NULL PRIMARY KEY,
+    command character varying NOT NULL,
+    processed_time timestamp with time zone NOT NULL DEFAULT now(),
+    status character varying NOT NULL DEFAULT 'applied',
+    error_message character varying NOT NULL DEFAULT '',
+    event_type character varying NOT NULL DEFAULT '',
+    event_payload character varying NOT NULL DEFAULT ''
+);
+CREATE INDEX ix_processed_command_time ON "yas_processed_command" USING btree ("processed_time");
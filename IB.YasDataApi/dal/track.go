@@ -0,0 +1,236 @@
+package dal
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+
+	"IB.YasDataApi/abstract"
+	"IB.YasDataApi/abstract/command"
+	"IB.YasDataApi/dal/yasdb"
+)
+
+// Fixed point scales of the track point columns, see yas_track_point
+//
+const (
+	positionScale = 1e7
+	motionScale = 10
+)
+
+// Returns tracks of the user without points, the newest first.
+// Returns ErrUserNotFound if there is no user with the token
+//
+func (dal *Dal) QueryTracks(ctx context.Context, token string) ([]abstract.Track, error) {
+	yasTracks, err := queryDb(
+		ctx,
+		dal.Pool,
+		"ListTracks",
+		func(query *yasdb.Queries, ctx context.Context) ([]yasdb.YasTrack, error) {
+			return query.ListTracks(ctx, token)
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(yasTracks) == 0 {
+		if _, err := dal.QueryUserByToken(ctx, token); err == pgx.ErrNoRows {
+			return nil, ErrUserNotFound
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	tracks := make([]abstract.Track, 0, len(yasTracks))
+	for _, t := range yasTracks {
+		tracks = append(tracks, toTrack(t))
+	}
+	return tracks, nil
+}
+
+// Returns the track of the user with one page of its points in time order.
+// Returns ErrUserNotFound if there is no user with the token and ErrTrackNotFound if the user has no such track
+//
+func (dal *Dal) QueryTrack(ctx context.Context, token string, trackId int32, pointQuery abstract.TrackPointQuery) (abstract.TrackPage, error) {
+	yasTrack, err := queryDb(
+		ctx,
+		dal.Pool,
+		"GetTrack",
+		func(query *yasdb.Queries, ctx context.Context) (yasdb.YasTrack, error) {
+			return query.GetTrack(ctx, yasdb.GetTrackParams{ PublicID: token, TrackID: trackId })
+		})
+	if err == pgx.ErrNoRows {
+		if _, err := dal.QueryUserByToken(ctx, token); err == pgx.ErrNoRows {
+			return abstract.TrackPage{}, ErrUserNotFound
+		} else if err != nil {
+			return abstract.TrackPage{}, err
+		}
+		return abstract.TrackPage{}, ErrTrackNotFound
+	}
+	if err != nil {
+		return abstract.TrackPage{}, err
+	}
+
+	// Get raw points from DB, one point more than the limit starts the next page
+	//
+	rowLimit := int32(math.MaxInt32)
+	if pointQuery.Limit > 0 && pointQuery.Limit < math.MaxInt32 {
+		rowLimit = pointQuery.Limit + 1
+	}
+	timeTo := pointQuery.To
+	if timeTo.IsZero() {
+		timeTo = maxUploadTime
+	}
+	yasPoints, err := queryDb(
+		ctx,
+		dal.Pool,
+		"ListTrackPoints",
+		func(query *yasdb.Queries, ctx context.Context) ([]yasdb.YasTrackPoint, error) {
+			return query.ListTrackPoints(ctx, yasdb.ListTrackPointsParams {
+				TrackID: trackId,
+				TimeFrom: pointQuery.From,
+				TimeTo: timeTo,
+				RowLimit: rowLimit,
+			})
+		})
+	if err != nil {
+		return abstract.TrackPage{}, err
+	}
+
+	page := abstract.TrackPage{ Track: toTrack(yasTrack) }
+	if pointQuery.Limit > 0 && len(yasPoints) > int(pointQuery.Limit) {
+		next := yasPoints[pointQuery.Limit].PointTime
+		page.Next = &next
+		yasPoints = yasPoints[:pointQuery.Limit]
+	}
+	page.Track.Points = make([]abstract.TrackPoint, 0, len(yasPoints))
+	for _, p := range yasPoints {
+		page.Track.Points = append(page.Track.Points, toTrackPoint(p))
+	}
+	return page, nil
+}
+
+// Appends the batch to the user track with the key, the track is created by its first batch.
+// Points which are not after the end of the stored track are skipped, so the batch uploaded
+// again is not stored twice. Points are bulk-inserted with COPY.
+// Returns the track id and the number of stored points, ErrUserNotFound if there is no user with the token
+//
+func (dal *Dal) ExecAppendTrackPoints(ctx context.Context, commandId string, t command.AppendTrackPoints, event func(trackId int32, points int) CommandEvent) (int32, int, error) {
+	var trackId int32
+	var appended int
+	err := execCommand(
+		ctx,
+		dal.Pool,
+		"ExecAppendTrackPoints",
+		commandId,
+		command.CmdAppendTrackPoints,
+		func(query *yasdb.Queries, ctx context.Context) error {
+			track, err := query.UpsertTrack(ctx, yasdb.UpsertTrackParams {
+				TrackKey: t.TrackKey,
+				TrackName: t.TrackName,
+				PublicID: t.Token,
+			})
+			if err == pgx.ErrNoRows {
+				return ErrUserNotFound
+			}
+			if err != nil {
+				return err
+			}
+			trackId = track.TrackID
+
+			// Postgres keeps microseconds, points are compared at the stored precision
+			//
+			endTime := track.EndTime
+			points := make([]yasdb.AddTrackPointsParams, 0, len(t.Points))
+			for _, p := range t.Points {
+				pointTime := p.Time.Truncate(time.Microsecond)
+				if endTime.Valid && !pointTime.After(endTime.Time) {
+					continue
+				}
+				endTime = sql.NullTime{ Time: pointTime, Valid: true }
+				points = append(points, yasdb.AddTrackPointsParams {
+					PointTime: pointTime,
+					TrackID: trackId,
+					LatE7: int32(math.Round(p.Lat * positionScale)),
+					LonE7: int32(math.Round(p.Lon * positionScale)),
+					SogE1: toFixedMotion(p.Sog),
+					CogE1: toFixedMotion(p.Cog),
+					HeadingE1: toFixedMotion(p.Heading),
+				})
+			}
+			appended = len(points)
+			if appended == 0 {
+				return nil
+			}
+
+			if _, err := query.AddTrackPoints(ctx, points); err != nil {
+				return err
+			}
+			return query.UpdateTrackSpan(ctx, yasdb.UpdateTrackSpanParams {
+				StartTime: points[0].PointTime,
+				EndTime: endTime.Time,
+				PointCount: int32(appended),
+				TrackID: trackId,
+			})
+		},
+		func() CommandEvent { return event(trackId, appended) })
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return trackId, appended, nil
+}
+
+func toTrack(t yasdb.YasTrack) abstract.Track {
+	return abstract.Track {
+		TrackId: t.TrackID,
+		UserId: t.UserID,
+		TrackKey: t.TrackKey,
+		TrackName: t.TrackName,
+		CreateTime: t.CreateTime,
+		StartTime: fromNullTime(t.StartTime),
+		EndTime: fromNullTime(t.EndTime),
+		PointCount: t.PointCount,
+	}
+}
+
+func toTrackPoint(p yasdb.YasTrackPoint) abstract.TrackPoint {
+	return abstract.TrackPoint {
+		Time: p.PointTime,
+		Lat: float64(p.LatE7) / positionScale,
+		Lon: float64(p.LonE7) / positionScale,
+		Sog: fromFixedMotion(p.SogE1),
+		Cog: fromFixedMotion(p.CogE1),
+		Heading: fromFixedMotion(p.HeadingE1),
+	}
+}
+
+func fromNullTime(value sql.NullTime) *time.Time {
+	if !value.Valid {
+		return nil
+	}
+	return &value.Time
+}
+
+// Values out of the column range are stored as missing
+//
+func toFixedMotion(value *float64) sql.NullInt16 {
+	if value == nil {
+		return sql.NullInt16{}
+	}
+	fixed := math.Round(*value * motionScale)
+	if fixed < math.MinInt16 || fixed > math.MaxInt16 || math.IsNaN(fixed) {
+		return sql.NullInt16{}
+	}
+	return sql.NullInt16{ Int16: int16(fixed), Valid: true }
+}
+
+func fromFixedMotion(value sql.NullInt16) *float64 {
+	if !value.Valid {
+		return nil
+	}
+	result := float64(value.Int16) / motionScale
+	return &result
+}
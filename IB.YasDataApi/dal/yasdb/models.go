@@ -5,9 +5,20 @@
 package yasdb
 
 import (
+	"database/sql"
 	"time"
 )
 
+type YasProcessedCommand struct {
+	CommandID     string
+	Command       string
+	ProcessedTime time.Time
+	Status        string
+	ErrorMessage  string
+	EventType     string
+	EventPayload  string
+}
+
 type YasRoute struct {
 	RouteID    int32
 	UserID     int64
@@ -15,6 +26,27 @@ type YasRoute struct {
 	UploadTime time.Time
 }
 
+type YasTrack struct {
+	TrackID    int32
+	UserID     int64
+	TrackKey   string
+	TrackName  string
+	CreateTime time.Time
+	StartTime  sql.NullTime
+	EndTime    sql.NullTime
+	PointCount int32
+}
+
+type YasTrackPoint struct {
+	PointTime time.Time
+	TrackID   int32
+	LatE7     int32
+	LonE7     int32
+	SogE1     sql.NullInt16
+	CogE1     sql.NullInt16
+	HeadingE1 sql.NullInt16
+}
+
 type YasUser struct {
 	UserID       int32
 	PublicID     string
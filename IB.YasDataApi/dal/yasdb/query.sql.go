@@ -7,8 +7,48 @@ package yasdb
 
 import (
 	"context"
+	"database/sql"
+	"time"
 )
 
+const addFailedCommand = `-- name: AddFailedCommand :exec
+INSERT INTO yas_processed_command (command_id, command, processed_time, status, error_message) 
+    VALUES ($1, $2, now(), 'failed', $3)
+ON CONFLICT (command_id) DO UPDATE SET processed_time = now(), status = 'failed', error_message = $3
+    WHERE yas_processed_command.status <> 'applied'
+`
+
+type AddFailedCommandParams struct {
+	CommandID    string
+	Command      string
+	ErrorMessage string
+}
+
+func (q *Queries) AddFailedCommand(ctx context.Context, arg AddFailedCommandParams) error {
+	_, err := q.db.Exec(ctx, addFailedCommand, arg.CommandID, arg.Command, arg.ErrorMessage)
+	return err
+}
+
+const addProcessedCommand = `-- name: AddProcessedCommand :execrows
+INSERT INTO yas_processed_command (command_id, command, processed_time, status, error_message) 
+    VALUES ($1, $2, now(), 'applied', '')
+ON CONFLICT (command_id) DO UPDATE SET processed_time = now(), status = 'applied', error_message = '', event_type = '', event_payload = ''
+    WHERE yas_processed_command.status <> 'applied'
+`
+
+type AddProcessedCommandParams struct {
+	CommandID string
+	Command   string
+}
+
+func (q *Queries) AddProcessedCommand(ctx context.Context, arg AddProcessedCommandParams) (int64, error) {
+	result, err := q.db.Exec(ctx, addProcessedCommand, arg.CommandID, arg.Command)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const addRoute = `-- name: AddRoute :one
 INSERT INTO yas_route (user_id, route_name, upload_time) VALUES ($1, $2, now())
 RETURNING route_id
@@ -26,6 +66,16 @@ func (q *Queries) AddRoute(ctx context.Context, arg AddRouteParams) (int32, erro
 	return route_id, err
 }
 
+type AddTrackPointsParams struct {
+	PointTime time.Time
+	TrackID   int32
+	LatE7     int32
+	LonE7     int32
+	SogE1     sql.NullInt16
+	CogE1     sql.NullInt16
+	HeadingE1 sql.NullInt16
+}
+
 const addWaypoint = `-- name: AddWaypoint :exec
 INSERT INTO yas_waypoint (route_id, waypoint_name, lat, lon, order_id) VALUES ($1, $2, $3, $4, $5)
 `
@@ -49,6 +99,25 @@ func (q *Queries) AddWaypoint(ctx context.Context, arg AddWaypointParams) error
 	return err
 }
 
+type AddWaypointsParams struct {
+	RouteID      int64
+	WaypointName string
+	Lat          float64
+	Lon          float64
+	OrderID      int32
+}
+
+const countRouteWaypoints = `-- name: CountRouteWaypoints :one
+SELECT count(*) FROM yas_waypoint WHERE route_id = $1
+`
+
+func (q *Queries) CountRouteWaypoints(ctx context.Context, routeID int64) (int64, error) {
+	row := q.db.QueryRow(ctx, countRouteWaypoints, routeID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createUser = `-- name: CreateUser :exec
 INSERT INTO yas_user (public_id, telegram_id, user_name, register_time)
     VALUES ($1, $2, $3, now())
@@ -66,6 +135,18 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) error {
 	return err
 }
 
+const deleteProcessedCommands = `-- name: DeleteProcessedCommands :execrows
+DELETE FROM yas_processed_command WHERE processed_time < $1
+`
+
+func (q *Queries) DeleteProcessedCommands(ctx context.Context, processedTime time.Time) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteProcessedCommands, processedTime)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const deleteRoute = `-- name: DeleteRoute :exec
 DELETE FROM yas_route WHERE route_id = $1 AND user_id = (SELECT user_id FROM yas_user WHERE public_id = $2)
 `
@@ -80,6 +161,101 @@ func (q *Queries) DeleteRoute(ctx context.Context, arg DeleteRouteParams) error
 	return err
 }
 
+const deleteRouteWaypoints = `-- name: DeleteRouteWaypoints :exec
+DELETE FROM yas_waypoint WHERE route_id = $1
+`
+
+func (q *Queries) DeleteRouteWaypoints(ctx context.Context, routeID int64) error {
+	_, err := q.db.Exec(ctx, deleteRouteWaypoints, routeID)
+	return err
+}
+
+const deleteWaypoint = `-- name: DeleteWaypoint :execrows
+DELETE FROM yas_waypoint WHERE route_id = $1 AND waypoint_id = $2
+`
+
+type DeleteWaypointParams struct {
+	RouteID    int64
+	WaypointID int32
+}
+
+func (q *Queries) DeleteWaypoint(ctx context.Context, arg DeleteWaypointParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteWaypoint, arg.RouteID, arg.WaypointID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const getCommand = `-- name: GetCommand :one
+SELECT command_id, command, processed_time, status, error_message, event_type, event_payload FROM yas_processed_command WHERE command_id = $1
+`
+
+func (q *Queries) GetCommand(ctx context.Context, commandID string) (YasProcessedCommand, error) {
+	row := q.db.QueryRow(ctx, getCommand, commandID)
+	var i YasProcessedCommand
+	err := row.Scan(
+		&i.CommandID,
+		&i.Command,
+		&i.ProcessedTime,
+		&i.Status,
+		&i.ErrorMessage,
+		&i.EventType,
+		&i.EventPayload,
+	)
+	return i, err
+}
+
+const getRoute = `-- name: GetRoute :one
+SELECT r.route_id, r.user_id, r.route_name, r.upload_time FROM yas_route r
+JOIN yas_user u ON r.user_id = u.user_id
+WHERE u.public_id = $1 AND r.route_id = $2
+`
+
+type GetRouteParams struct {
+	PublicID string
+	RouteID  int32
+}
+
+func (q *Queries) GetRoute(ctx context.Context, arg GetRouteParams) (YasRoute, error) {
+	row := q.db.QueryRow(ctx, getRoute, arg.PublicID, arg.RouteID)
+	var i YasRoute
+	err := row.Scan(
+		&i.RouteID,
+		&i.UserID,
+		&i.RouteName,
+		&i.UploadTime,
+	)
+	return i, err
+}
+
+const getTrack = `-- name: GetTrack :one
+SELECT t.track_id, t.user_id, t.track_key, t.track_name, t.create_time, t.start_time, t.end_time, t.point_count FROM yas_track t
+JOIN yas_user u ON t.user_id = u.user_id
+WHERE u.public_id = $1 AND t.track_id = $2
+`
+
+type GetTrackParams struct {
+	PublicID string
+	TrackID  int32
+}
+
+func (q *Queries) GetTrack(ctx context.Context, arg GetTrackParams) (YasTrack, error) {
+	row := q.db.QueryRow(ctx, getTrack, arg.PublicID, arg.TrackID)
+	var i YasTrack
+	err := row.Scan(
+		&i.TrackID,
+		&i.UserID,
+		&i.TrackKey,
+		&i.TrackName,
+		&i.CreateTime,
+		&i.StartTime,
+		&i.EndTime,
+		&i.PointCount,
+	)
+	return i, err
+}
+
 const getUser = `-- name: GetUser :one
 SELECT user_id, public_id, telegram_id, COALESCE(user_name, '') as user_name, register_time FROM yas_user WHERE telegram_id = $1
 `
@@ -97,15 +273,142 @@ func (q *Queries) GetUser(ctx context.Context, telegramID int64) (YasUser, error
 	return i, err
 }
 
-const listRoutes = `-- name: ListRoutes :many
+const getUserByToken = `-- name: GetUserByToken :one
+SELECT user_id, public_id, telegram_id, COALESCE(user_name, '') as user_name, register_time FROM yas_user WHERE public_id = $1
+`
+
+func (q *Queries) GetUserByToken(ctx context.Context, publicID string) (YasUser, error) {
+	row := q.db.QueryRow(ctx, getUserByToken, publicID)
+	var i YasUser
+	err := row.Scan(
+		&i.UserID,
+		&i.PublicID,
+		&i.TelegramID,
+		&i.UserName,
+		&i.RegisterTime,
+	)
+	return i, err
+}
+
+const getWaypoint = `-- name: GetWaypoint :one
+SELECT waypoint_id, route_id, COALESCE(waypoint_name, '') as waypoint_name, lat, lon, order_id FROM yas_waypoint
+WHERE route_id = $1 AND waypoint_id = $2
+`
+
+type GetWaypointParams struct {
+	RouteID    int64
+	WaypointID int32
+}
+
+func (q *Queries) GetWaypoint(ctx context.Context, arg GetWaypointParams) (YasWaypoint, error) {
+	row := q.db.QueryRow(ctx, getWaypoint, arg.RouteID, arg.WaypointID)
+	var i YasWaypoint
+	err := row.Scan(
+		&i.WaypointID,
+		&i.RouteID,
+		&i.WaypointName,
+		&i.Lat,
+		&i.Lon,
+		&i.OrderID,
+	)
+	return i, err
+}
+
+const insertWaypoint = `-- name: InsertWaypoint :one
+INSERT INTO yas_waypoint (route_id, waypoint_name, lat, lon, order_id) VALUES ($1, $2, $3, $4, $5)
+RETURNING waypoint_id
+`
+
+type InsertWaypointParams struct {
+	RouteID      int64
+	WaypointName string
+	Lat          float64
+	Lon          float64
+	OrderID      int32
+}
+
+func (q *Queries) InsertWaypoint(ctx context.Context, arg InsertWaypointParams) (int32, error) {
+	row := q.db.QueryRow(ctx, insertWaypoint,
+		arg.RouteID,
+		arg.WaypointName,
+		arg.Lat,
+		arg.Lon,
+		arg.OrderID,
+	)
+	var waypoint_id int32
+	err := row.Scan(&waypoint_id)
+	return waypoint_id, err
+}
+
+const listRouteWaypoints = `-- name: ListRouteWaypoints :many
+SELECT waypoint_id, route_id, COALESCE(waypoint_name, '') as waypoint_name, lat, lon, order_id FROM yas_waypoint
+WHERE route_id = $1
+ORDER BY order_id ASC, waypoint_id ASC
+`
+
+func (q *Queries) ListRouteWaypoints(ctx context.Context, routeID int64) ([]YasWaypoint, error) {
+	rows, err := q.db.Query(ctx, listRouteWaypoints, routeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []YasWaypoint
+	for rows.Next() {
+		var i YasWaypoint
+		if err := rows.Scan(
+			&i.WaypointID,
+			&i.RouteID,
+			&i.WaypointName,
+			&i.Lat,
+			&i.Lon,
+			&i.OrderID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listRoutesByName = `-- name: ListRoutesByName :many
 SELECT r.route_id, r.user_id, r.route_name, r.upload_time FROM yas_route r
-JOIN yas_user u ON r.user_id = u.user_id 
-WHERE  u.public_id = $1
-ORDER BY upload_time DESC
+JOIN yas_user u ON r.user_id = u.user_id
+WHERE u.public_id = $1
+    AND r.route_name ILIKE $2
+    AND r.upload_time >= $3 AND r.upload_time < $4
+    AND (SELECT count(*) FROM yas_waypoint wp WHERE wp.route_id = r.route_id) BETWEEN $5::bigint AND $6::bigint
+    AND (r.route_name, r.route_id) > ($7::text, $8::integer)
+ORDER BY r.route_name ASC, r.route_id ASC
+LIMIT $9
 `
 
-func (q *Queries) ListRoutes(ctx context.Context, publicID string) ([]YasRoute, error) {
-	rows, err := q.db.Query(ctx, listRoutes, publicID)
+type ListRoutesByNameParams struct {
+	PublicID     string
+	NamePattern  string
+	UploadedFrom time.Time
+	UploadedTo   time.Time
+	MinWaypoints int64
+	MaxWaypoints int64
+	CursorName   string
+	CursorID     int32
+	RowLimit     int32
+}
+
+func (q *Queries) ListRoutesByName(ctx context.Context, arg ListRoutesByNameParams) ([]YasRoute, error) {
+	rows, err := q.db.Query(ctx, listRoutesByName,
+		arg.PublicID,
+		arg.NamePattern,
+		arg.UploadedFrom,
+		arg.UploadedTo,
+		arg.MinWaypoints,
+		arg.MaxWaypoints,
+		arg.CursorName,
+		arg.CursorID,
+		arg.RowLimit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -129,21 +432,42 @@ func (q *Queries) ListRoutes(ctx context.Context, publicID string) ([]YasRoute,
 	return items, nil
 }
 
-const listRoutesWithLimit = `-- name: ListRoutesWithLimit :many
+const listRoutesNewest = `-- name: ListRoutesNewest :many
 SELECT r.route_id, r.user_id, r.route_name, r.upload_time FROM yas_route r
-JOIN yas_user u ON r.user_id = u.user_id 
-WHERE  u.public_id = $1
-ORDER BY upload_time DESC
-LIMIT $2
+JOIN yas_user u ON r.user_id = u.user_id
+WHERE u.public_id = $1
+    AND r.route_name ILIKE $2
+    AND r.upload_time >= $3 AND r.upload_time < $4
+    AND (SELECT count(*) FROM yas_waypoint wp WHERE wp.route_id = r.route_id) BETWEEN $5::bigint AND $6::bigint
+    AND (r.upload_time, r.route_id) < ($7::timestamptz, $8::integer)
+ORDER BY r.upload_time DESC, r.route_id DESC
+LIMIT $9
 `
 
-type ListRoutesWithLimitParams struct {
-	PublicID string
-	Limit    int32
+type ListRoutesNewestParams struct {
+	PublicID     string
+	NamePattern  string
+	UploadedFrom time.Time
+	UploadedTo   time.Time
+	MinWaypoints int64
+	MaxWaypoints int64
+	CursorTime   time.Time
+	CursorID     int32
+	RowLimit     int32
 }
 
-func (q *Queries) ListRoutesWithLimit(ctx context.Context, arg ListRoutesWithLimitParams) ([]YasRoute, error) {
-	rows, err := q.db.Query(ctx, listRoutesWithLimit, arg.PublicID, arg.Limit)
+func (q *Queries) ListRoutesNewest(ctx context.Context, arg ListRoutesNewestParams) ([]YasRoute, error) {
+	rows, err := q.db.Query(ctx, listRoutesNewest,
+		arg.PublicID,
+		arg.NamePattern,
+		arg.UploadedFrom,
+		arg.UploadedTo,
+		arg.MinWaypoints,
+		arg.MaxWaypoints,
+		arg.CursorTime,
+		arg.CursorID,
+		arg.RowLimit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -167,16 +491,73 @@ func (q *Queries) ListRoutesWithLimit(ctx context.Context, arg ListRoutesWithLim
 	return items, nil
 }
 
-const listWaypoints = `-- name: ListWaypoints :many
-SELECT wp.waypoint_id, wp.route_id, COALESCE(wp.waypoint_name, '') as waypoint_name, wp.lat, wp.lon, wp.order_id FROM yas_waypoint wp
-JOIN yas_route r ON wp.route_id = r.route_id
+const listRoutesOldest = `-- name: ListRoutesOldest :many
+SELECT r.route_id, r.user_id, r.route_name, r.upload_time FROM yas_route r
 JOIN yas_user u ON r.user_id = u.user_id
 WHERE u.public_id = $1
-ORDER BY wp.order_id ASC, wp.waypoint_id ASC
+    AND r.route_name ILIKE $2
+    AND r.upload_time >= $3 AND r.upload_time < $4
+    AND (SELECT count(*) FROM yas_waypoint wp WHERE wp.route_id = r.route_id) BETWEEN $5::bigint AND $6::bigint
+    AND (r.upload_time, r.route_id) > ($7::timestamptz, $8::integer)
+ORDER BY r.upload_time ASC, r.route_id ASC
+LIMIT $9
 `
 
-func (q *Queries) ListWaypoints(ctx context.Context, publicID string) ([]YasWaypoint, error) {
-	rows, err := q.db.Query(ctx, listWaypoints, publicID)
+type ListRoutesOldestParams struct {
+	PublicID     string
+	NamePattern  string
+	UploadedFrom time.Time
+	UploadedTo   time.Time
+	MinWaypoints int64
+	MaxWaypoints int64
+	CursorTime   time.Time
+	CursorID     int32
+	RowLimit     int32
+}
+
+func (q *Queries) ListRoutesOldest(ctx context.Context, arg ListRoutesOldestParams) ([]YasRoute, error) {
+	rows, err := q.db.Query(ctx, listRoutesOldest,
+		arg.PublicID,
+		arg.NamePattern,
+		arg.UploadedFrom,
+		arg.UploadedTo,
+		arg.MinWaypoints,
+		arg.MaxWaypoints,
+		arg.CursorTime,
+		arg.CursorID,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []YasRoute
+	for rows.Next() {
+		var i YasRoute
+		if err := rows.Scan(
+			&i.RouteID,
+			&i.UserID,
+			&i.RouteName,
+			&i.UploadTime,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listRoutesWaypoints = `-- name: ListRoutesWaypoints :many
+SELECT waypoint_id, route_id, COALESCE(waypoint_name, '') as waypoint_name, lat, lon, order_id FROM yas_waypoint
+WHERE route_id = ANY($1::bigint[])
+ORDER BY route_id ASC, order_id ASC, waypoint_id ASC
+`
+
+func (q *Queries) ListRoutesWaypoints(ctx context.Context, routeIds []int64) ([]YasWaypoint, error) {
+	rows, err := q.db.Query(ctx, listRoutesWaypoints, routeIds)
 	if err != nil {
 		return nil, err
 	}
@@ -202,6 +583,132 @@ func (q *Queries) ListWaypoints(ctx context.Context, publicID string) ([]YasWayp
 	return items, nil
 }
 
+const listTrackPoints = `-- name: ListTrackPoints :many
+SELECT point_time, track_id, lat_e7, lon_e7, sog_e1, cog_e1, heading_e1 FROM yas_track_point
+WHERE track_id = $1 AND point_time >= $2 AND point_time < $3
+ORDER BY point_time ASC
+LIMIT $4
+`
+
+type ListTrackPointsParams struct {
+	TrackID  int32
+	TimeFrom time.Time
+	TimeTo   time.Time
+	RowLimit int32
+}
+
+func (q *Queries) ListTrackPoints(ctx context.Context, arg ListTrackPointsParams) ([]YasTrackPoint, error) {
+	rows, err := q.db.Query(ctx, listTrackPoints,
+		arg.TrackID,
+		arg.TimeFrom,
+		arg.TimeTo,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []YasTrackPoint
+	for rows.Next() {
+		var i YasTrackPoint
+		if err := rows.Scan(
+			&i.PointTime,
+			&i.TrackID,
+			&i.LatE7,
+			&i.LonE7,
+			&i.SogE1,
+			&i.CogE1,
+			&i.HeadingE1,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTracks = `-- name: ListTracks :many
+SELECT t.track_id, t.user_id, t.track_key, t.track_name, t.create_time, t.start_time, t.end_time, t.point_count FROM yas_track t
+JOIN yas_user u ON t.user_id = u.user_id
+WHERE u.public_id = $1
+ORDER BY t.track_id DESC
+`
+
+func (q *Queries) ListTracks(ctx context.Context, publicID string) ([]YasTrack, error) {
+	rows, err := q.db.Query(ctx, listTracks, publicID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []YasTrack
+	for rows.Next() {
+		var i YasTrack
+		if err := rows.Scan(
+			&i.TrackID,
+			&i.UserID,
+			&i.TrackKey,
+			&i.TrackName,
+			&i.CreateTime,
+			&i.StartTime,
+			&i.EndTime,
+			&i.PointCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const lockRoute = `-- name: LockRoute :one
+SELECT r.route_id FROM yas_route r
+JOIN yas_user u ON r.user_id = u.user_id
+WHERE u.public_id = $1 AND r.route_id = $2
+FOR UPDATE OF r
+`
+
+type LockRouteParams struct {
+	PublicID string
+	RouteID  int32
+}
+
+func (q *Queries) LockRoute(ctx context.Context, arg LockRouteParams) (int32, error) {
+	row := q.db.QueryRow(ctx, lockRoute, arg.PublicID, arg.RouteID)
+	var route_id int32
+	err := row.Scan(&route_id)
+	return route_id, err
+}
+
+const moveWaypoint = `-- name: MoveWaypoint :execrows
+UPDATE yas_waypoint SET lat = $3, lon = $4 WHERE route_id = $1 AND waypoint_id = $2
+`
+
+type MoveWaypointParams struct {
+	RouteID    int64
+	WaypointID int32
+	Lat        float64
+	Lon        float64
+}
+
+func (q *Queries) MoveWaypoint(ctx context.Context, arg MoveWaypointParams) (int64, error) {
+	result, err := q.db.Exec(ctx, moveWaypoint,
+		arg.RouteID,
+		arg.WaypointID,
+		arg.Lat,
+		arg.Lon,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const renameRouteById = `-- name: RenameRouteById :exec
 UPDATE yas_route SET route_name = $3 WHERE route_id = $1 AND user_id = $2
 `
@@ -231,3 +738,134 @@ func (q *Queries) RenameRouteByToken(ctx context.Context, arg RenameRouteByToken
 	_, err := q.db.Exec(ctx, renameRouteByToken, arg.RouteID, arg.PublicID, arg.RouteName)
 	return err
 }
+
+const renameWaypoint = `-- name: RenameWaypoint :execrows
+UPDATE yas_waypoint SET waypoint_name = $3 WHERE route_id = $1 AND waypoint_id = $2
+`
+
+type RenameWaypointParams struct {
+	RouteID      int64
+	WaypointID   int32
+	WaypointName string
+}
+
+func (q *Queries) RenameWaypoint(ctx context.Context, arg RenameWaypointParams) (int64, error) {
+	result, err := q.db.Exec(ctx, renameWaypoint, arg.RouteID, arg.WaypointID, arg.WaypointName)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const renumberWaypoints = `-- name: RenumberWaypoints :exec
+UPDATE yas_waypoint SET order_id = n.order_id
+FROM (
+    SELECT waypoint_id, (row_number() OVER (ORDER BY order_id, waypoint_id) - 1)::integer AS order_id
+    FROM yas_waypoint WHERE route_id = $1
+) n
+WHERE yas_waypoint.route_id = $1 AND yas_waypoint.waypoint_id = n.waypoint_id AND yas_waypoint.order_id <> n.order_id
+`
+
+func (q *Queries) RenumberWaypoints(ctx context.Context, routeID int64) error {
+	_, err := q.db.Exec(ctx, renumberWaypoints, routeID)
+	return err
+}
+
+const reorderWaypoints = `-- name: ReorderWaypoints :execrows
+UPDATE yas_waypoint SET order_id = (o.ordinal - 1)::integer
+FROM unnest($1::integer[]) WITH ORDINALITY AS o(waypoint_id, ordinal)
+WHERE yas_waypoint.route_id = $2 AND yas_waypoint.waypoint_id = o.waypoint_id
+`
+
+type ReorderWaypointsParams struct {
+	WaypointIds []int32
+	RouteID     int64
+}
+
+func (q *Queries) ReorderWaypoints(ctx context.Context, arg ReorderWaypointsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, reorderWaypoints, arg.WaypointIds, arg.RouteID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const setCommandEvent = `-- name: SetCommandEvent :exec
+UPDATE yas_processed_command SET event_type = $2, event_payload = $3 WHERE command_id = $1
+`
+
+type SetCommandEventParams struct {
+	CommandID    string
+	EventType    string
+	EventPayload string
+}
+
+func (q *Queries) SetCommandEvent(ctx context.Context, arg SetCommandEventParams) error {
+	_, err := q.db.Exec(ctx, setCommandEvent, arg.CommandID, arg.EventType, arg.EventPayload)
+	return err
+}
+
+const shiftWaypoints = `-- name: ShiftWaypoints :exec
+UPDATE yas_waypoint SET order_id = order_id + 1 WHERE route_id = $1 AND order_id >= $2
+`
+
+type ShiftWaypointsParams struct {
+	RouteID int64
+	OrderID int32
+}
+
+func (q *Queries) ShiftWaypoints(ctx context.Context, arg ShiftWaypointsParams) error {
+	_, err := q.db.Exec(ctx, shiftWaypoints, arg.RouteID, arg.OrderID)
+	return err
+}
+
+const updateTrackSpan = `-- name: UpdateTrackSpan :exec
+UPDATE yas_track SET
+    start_time = COALESCE(start_time, $1::timestamptz),
+    end_time = $2::timestamptz,
+    point_count = point_count + $3::integer
+WHERE track_id = $4
+`
+
+type UpdateTrackSpanParams struct {
+	StartTime  time.Time
+	EndTime    time.Time
+	PointCount int32
+	TrackID    int32
+}
+
+func (q *Queries) UpdateTrackSpan(ctx context.Context, arg UpdateTrackSpanParams) error {
+	_, err := q.db.Exec(ctx, updateTrackSpan,
+		arg.StartTime,
+		arg.EndTime,
+		arg.PointCount,
+		arg.TrackID,
+	)
+	return err
+}
+
+const upsertTrack = `-- name: UpsertTrack :one
+INSERT INTO yas_track (user_id, track_key, track_name, create_time)
+SELECT u.user_id, $1::text, $2::text, now() FROM yas_user u WHERE u.public_id = $3
+ON CONFLICT (user_id, track_key) DO UPDATE
+    SET track_name = CASE WHEN EXCLUDED.track_name = '' THEN yas_track.track_name ELSE EXCLUDED.track_name END
+RETURNING track_id, end_time
+`
+
+type UpsertTrackParams struct {
+	TrackKey  string
+	TrackName string
+	PublicID  string
+}
+
+type UpsertTrackRow struct {
+	TrackID int32
+	EndTime sql.NullTime
+}
+
+func (q *Queries) UpsertTrack(ctx context.Context, arg UpsertTrackParams) (UpsertTrackRow, error) {
+	row := q.db.QueryRow(ctx, upsertTrack, arg.TrackKey, arg.TrackName, arg.PublicID)
+	var i UpsertTrackRow
+	err := row.Scan(&i.TrackID, &i.EndTime)
+	return i, err
+}
@@ -0,0 +1,124 @@
+package navigation
+
+import (
+	"math"
+)
+
+// Mean Earth radius of the WGS 84 ellipsoid, the sphere used by all calculations
+//
+const (
+	EarthRadiusMeters = 6371008.8
+	MetersPerNm = 1852.0
+	EarthRadiusNm = EarthRadiusMeters / MetersPerNm
+)
+
+// Positions closer than this (radians) to the opposite side of the Earth are antipodal
+//
+const antipodalTolerance = 1e-9
+
+// Position in decimal degrees, north and east are positive
+//
+type Position struct {
+	Lat float64
+	Lon float64
+}
+
+// Great-circle distance in nautical miles, haversine formula
+//
+func Distance(from Position, to Position) float64 {
+	lat1, lat2 := radians(from.Lat), radians(to.Lat)
+	dLat := lat2 - lat1
+	dLon := radians(to.Lon - from.Lon)
+
+	// Rounding makes a slightly greater than 1 for the antipodal positions
+	//
+	a := math.Sin(dLat / 2) * math.Sin(dLat / 2) + math.Cos(lat1) * math.Cos(lat2) * math.Sin(dLon / 2) * math.Sin(dLon / 2)
+	a = math.Min(a, 1)
+	return 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1 - a)) * EarthRadiusNm
+}
+
+// True bearing at the start of the great-circle leg, degrees 0..360.
+// Bearing between the same positions is 0
+//
+func InitialBearing(from Position, to Position) float64 {
+	lat1, lat2 := radians(from.Lat), radians(to.Lat)
+	dLon := radians(to.Lon - from.Lon)
+
+	y := math.Sin(dLon) * math.Cos(lat2)
+	x := math.Cos(lat1) * math.Sin(lat2) - math.Sin(lat1) * math.Cos(lat2) * math.Cos(dLon)
+	return normalizeBearing(degrees(math.Atan2(y, x)))
+}
+
+// True bearing at the end of the great-circle leg, degrees 0..360
+//
+func FinalBearing(from Position, to Position) float64 {
+	return normalizeBearing(InitialBearing(to, from) + 180)
+}
+
+// Position at the fraction of the great-circle leg, 0 is the start and 1 is the end.
+// Every great circle between antipodal positions is the shortest, the leg goes north
+// along the meridian of the start, over the pole and south along the opposite meridian
+//
+func Intermediate(from Position, to Position, fraction float64) Position {
+	x1, y1, z1 := unitVector(from)
+	x2, y2, z2 := unitVector(to)
+
+	// The angle is taken from the cross and the dot products, it is exact near 0 and pi
+	// where the haversine angle loses half of the digits
+	//
+	cx, cy, cz := y1 * z2 - z1 * y2, z1 * x2 - x1 * z2, x1 * y2 - y1 * x2
+	sinAngle := math.Sqrt(cx * cx + cy * cy + cz * cz)
+	cosAngle := x1 * x2 + y1 * y2 + z1 * z2
+	if sinAngle < antipodalTolerance {
+		if cosAngle > 0 {
+			return from
+		}
+		lat := from.Lat + fraction * 180
+		if lat <= 90 {
+			return Position{ Lat: lat, Lon: from.Lon }
+		}
+		return Position{ Lat: 180 - lat, Lon: normalizeLongitude(from.Lon + 180) }
+	}
+
+	angle := math.Atan2(sinAngle, cosAngle)
+	a := math.Sin((1 - fraction) * angle) / sinAngle
+	b := math.Sin(fraction * angle) / sinAngle
+	x := a * x1 + b * x2
+	y := a * y1 + b * y2
+	z := a * z1 + b * z2
+	return Position {
+		Lat: degrees(math.Atan2(z, math.Sqrt(x * x + y * y))),
+		Lon: degrees(math.Atan2(y, x)),
+	}
+}
+
+func unitVector(position Position) (float64, float64, float64) {
+	lat, lon := radians(position.Lat), radians(position.Lon)
+	return math.Cos(lat) * math.Cos(lon), math.Cos(lat) * math.Sin(lon), math.Sin(lat)
+}
+
+func radians(value float64) float64 {
+	return value * math.Pi / 180
+}
+
+func degrees(value float64) float64 {
+	return value * 180 / math.Pi
+}
+
+// Wraps longitude into -180..180
+//
+func normalizeLongitude(value float64) float64 {
+	value = math.Mod(value + 180, 360)
+	if value < 0 {
+		value += 360
+	}
+	return value - 180
+}
+
+func normalizeBearing(value float64) float64 {
+	value = math.Mod(value, 360)
+	if value < 0 {
+		value += 360
+	}
+	return value
+}
@@ -0,0 +1,137 @@
+package navigation
+
+import (
+	"math"
+	"testing"
+)
+
+// Aviation Formulary example: LAX 33°57'N 118°24'W to JFK 40°38'N 73°47'W is 0.623585 rad,
+// the initial course is 65.892°, 40% of the way is 38°40.167'N 101°37.570'W
+//
+var (
+	lax = Position{ Lat: 33 + 57.0 / 60, Lon: -(118 + 24.0 / 60) }
+	jfk = Position{ Lat: 40 + 38.0 / 60, Lon: -(73 + 47.0 / 60) }
+)
+
+// One degree of the great circle
+//
+const degreeNm = EarthRadiusNm * math.Pi / 180
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		name string
+		from Position
+		to   Position
+		want float64
+	}{
+		{ name: "same position", from: lax, to: lax, want: 0 },
+		{ name: "lax to jfk", from: lax, to: jfk, want: 0.623585 * EarthRadiusNm },
+		{ name: "jfk to lax", from: jfk, to: lax, want: 0.623585 * EarthRadiusNm },
+		{ name: "one degree of the equator", from: Position{ 0, 0 }, to: Position{ 0, 1 }, want: degreeNm },
+		{ name: "one degree of the meridian", from: Position{ 59.5, 24 }, to: Position{ 60.5, 24 }, want: degreeNm },
+		{ name: "across the antimeridian", from: Position{ 0, 179.5 }, to: Position{ 0, -179.5 }, want: degreeNm },
+		{ name: "antimeridian as 180 and -180", from: Position{ 10, 180 }, to: Position{ 10, -180 }, want: 0 },
+		{ name: "equator to pole", from: Position{ 0, 45 }, to: Position{ 90, 0 }, want: 90 * degreeNm },
+		{ name: "pole at any longitude", from: Position{ 90, 0 }, to: Position{ 90, 120 }, want: 0 },
+		{ name: "over the pole", from: Position{ 80, 0 }, to: Position{ 80, 180 }, want: 20 * degreeNm },
+		{ name: "pole to pole", from: Position{ 90, 0 }, to: Position{ -90, 0 }, want: 180 * degreeNm },
+		{ name: "antipodal on the equator", from: Position{ 0, 0 }, to: Position{ 0, 180 }, want: 180 * degreeNm },
+		{ name: "antipodal", from: Position{ 10, 170 }, to: Position{ -10, -10 }, want: 180 * degreeNm },
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := Distance(test.from, test.to); !(math.Abs(got - test.want) < 0.005) {
+				t.Errorf("distance = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestBearing(t *testing.T) {
+	tests := []struct {
+		name    string
+		from    Position
+		to      Position
+		initial float64
+		final   float64
+	}{
+		{ name: "lax to jfk", from: lax, to: jfk, initial: 65.8922, final: 93.8582 },
+		{ name: "north", from: Position{ 0, 0 }, to: Position{ 10, 0 }, initial: 0, final: 0 },
+		{ name: "south", from: Position{ 10, 0 }, to: Position{ -10, 0 }, initial: 180, final: 180 },
+		{ name: "east on the equator", from: Position{ 0, 0 }, to: Position{ 0, 90 }, initial: 90, final: 90 },
+		{ name: "west across the antimeridian", from: Position{ 0, -179.5 }, to: Position{ 0, 179.5 }, initial: 270, final: 270 },
+		{ name: "east across the antimeridian", from: Position{ 0, 179.5 }, to: Position{ 0, -179.5 }, initial: 90, final: 90 },
+		{ name: "east at 60N", from: Position{ 60, 0 }, to: Position{ 60, 10 }, initial: 85.6671, final: 94.3329 },
+		{ name: "north near the pole", from: Position{ 45, 30 }, to: Position{ 89.9, 30 }, initial: 0, final: 0 },
+		{ name: "from the north pole", from: Position{ 90, 0 }, to: Position{ 45, 30 }, initial: 150, final: 180 },
+		{ name: "over the pole", from: Position{ 80, 0 }, to: Position{ 80, 180 }, initial: 0, final: 180 },
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := InitialBearing(test.from, test.to); bearingDifference(got, test.initial) > 1e-4 {
+				t.Errorf("initial bearing = %v, want %v", got, test.initial)
+			}
+			if got := FinalBearing(test.from, test.to); bearingDifference(got, test.final) > 1e-4 {
+				t.Errorf("final bearing = %v, want %v", got, test.final)
+			}
+		})
+	}
+}
+
+func TestIntermediate(t *testing.T) {
+	tests := []struct {
+		name     string
+		from     Position
+		to       Position
+		fraction float64
+		want     Position
+	}{
+		{ name: "start", from: lax, to: jfk, fraction: 0, want: lax },
+		{ name: "end", from: lax, to: jfk, fraction: 1, want: jfk },
+		{ name: "40% of lax to jfk", from: lax, to: jfk, fraction: 0.4, want: Position{ 38 + 40.167 / 60, -(101 + 37.570 / 60) } },
+		{ name: "same position", from: jfk, to: jfk, fraction: 0.5, want: jfk },
+		{ name: "equator", from: Position{ 0, 10 }, to: Position{ 0, 50 }, fraction: 0.25, want: Position{ 0, 20 } },
+		{ name: "across the antimeridian", from: Position{ 0, 170 }, to: Position{ 0, -170 }, fraction: 0.75, want: Position{ 0, -175 } },
+		{ name: "over the pole", from: Position{ 60, 0 }, to: Position{ 60, 180 }, fraction: 0.5, want: Position{ 90, 0 } },
+		{ name: "antipodal on the equator", from: Position{ 0, 30 }, to: Position{ 0, -150 }, fraction: 0.5, want: Position{ 90, 0 } },
+		{ name: "antipodal before the pole", from: Position{ 10, 170 }, to: Position{ -10, -10 }, fraction: 0.25, want: Position{ 55, 170 } },
+		{ name: "antipodal after the pole", from: Position{ 10, 170 }, to: Position{ -10, -10 }, fraction: 0.75, want: Position{ 35, -10 } },
+		{ name: "antipodal end", from: Position{ 10, 170 }, to: Position{ -10, -10 }, fraction: 1, want: Position{ -10, -10 } },
+		{ name: "pole to pole", from: Position{ -90, 0 }, to: Position{ 90, 0 }, fraction: 0.5, want: Position{ 0, 0 } },
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := Intermediate(test.from, test.to, test.fraction)
+			if math.IsNaN(got.Lat) || math.IsNaN(got.Lon) || Distance(got, test.want) > 0.01 {
+				t.Errorf("intermediate = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestIntermediateNearlyAntipodal(t *testing.T) {
+	from := Position{ 59.44417, 24.76528 }
+	for _, offset := range []float64{ 0, 1e-9, 1e-7, 1e-5 } {
+		to := Position{ -from.Lat + offset, from.Lon - 180 }
+		for _, fraction := range []float64{ 0, 0.3, 0.5, 1 } {
+			got := Intermediate(from, to, fraction)
+			if math.IsNaN(got.Lat) || math.IsNaN(got.Lon) {
+				t.Fatalf("intermediate with offset %v at %v = %v", offset, fraction, got)
+			}
+			// Every point of the great circle leg splits it into the parts of the fraction
+			//
+			total := Distance(from, to)
+			if part := Distance(from, got); math.Abs(part - fraction * total) > 0.01 {
+				t.Errorf("offset %v: distance to the point at %v = %v, want %v", offset, fraction, part, fraction * total)
+			}
+		}
+	}
+}
+
+func bearingDifference(a float64, b float64) float64 {
+	difference := math.Abs(normalizeBearing(a) - normalizeBearing(b))
+	return math.Min(difference, 360 - difference)
+}
@@ -0,0 +1,129 @@
+package navigation
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"IB.YasDataApi/abstract"
+)
+
+var ErrInvalidSpeed = errors.New("speed must be positive")
+
+var ErrPlanTooLong = errors.New("route takes too long to sail")
+
+// Longest plan, elapsed times are converted to time.Duration
+//
+const maxPlanSeconds = float64(math.MaxInt64 / int64(time.Second))
+
+// Boat speed of the plan. Leg speeds override the speed of the legs, the leg number is the
+// number of its destination waypoint counting from 1: leg 1 goes from the first waypoint to the second
+//
+type PlanOptions struct {
+	SpeedKn float64
+	Start time.Time
+	LegSpeedsKn map[int]float64
+}
+
+// Timing plan of the route, times are in seconds from the start
+//
+type Plan struct {
+	RouteId         int32			`json:"routeId"`
+	RouteName       string			`json:"routeName"`
+	SpeedKn         float64			`json:"speedKn"`
+	Start           time.Time		`json:"start"`
+	Finish          time.Time		`json:"finish"`
+	TotalDistanceNm float64			`json:"totalDistanceNm"`
+	TotalSeconds    int64			`json:"totalSeconds"`
+	Waypoints       []PlanWaypoint	`json:"waypoints"`
+}
+
+// Waypoint of the plan with the leg which ends at it, the first waypoint is the start with leg 0
+//
+type PlanWaypoint struct {
+	WaypointId     int32		`json:"waypointId"`
+	WaypointName   string		`json:"waypointName"`
+	Lat            float64		`json:"lat"`
+	Lon            float64		`json:"lon"`
+	Leg            int			`json:"leg"`
+	LegDistanceNm  float64		`json:"legDistanceNm"`
+	LegSpeedKn     float64		`json:"legSpeedKn"`
+	LegSeconds     int64		`json:"legSeconds"`
+	DistanceNm     float64		`json:"distanceNm"`
+	ElapsedSeconds int64		`json:"elapsedSeconds"`
+	Eta            time.Time	`json:"eta"`
+}
+
+// Computes ETA of every waypoint sailing the great-circle legs at the speed of the options.
+// Distances are rounded to 0.01 nm, times to the second.
+// Returns ErrPlanTooLong if the route takes longer than time.Duration or the finish is after the year 9999
+//
+func PlanRoute(route abstract.Route, options PlanOptions) (Plan, error) {
+	if !(options.SpeedKn > 0) || math.IsInf(options.SpeedKn, 0) {
+		return Plan{}, ErrInvalidSpeed
+	}
+	for leg, speed := range options.LegSpeedsKn {
+		if leg < 1 || leg >= len(route.Waypoints) {
+			return Plan{}, fmt.Errorf("leg %d is not in the route of %d legs", leg, maxInt(len(route.Waypoints) - 1, 0))
+		}
+		if !(speed > 0) || math.IsInf(speed, 0) {
+			return Plan{}, fmt.Errorf("leg %d: %w", leg, ErrInvalidSpeed)
+		}
+	}
+
+	plan := Plan {
+		RouteId: route.RouteId,
+		RouteName: route.RouteName,
+		SpeedKn: options.SpeedKn,
+		Start: options.Start,
+		Finish: options.Start,
+		Waypoints: make([]PlanWaypoint, 0, len(route.Waypoints)),
+	}
+	distance, elapsed := 0.0, 0.0
+	for i, wp := range route.Waypoints {
+		point := PlanWaypoint {
+			WaypointId: wp.WaypointId,
+			WaypointName: wp.WaypointName,
+			Lat: wp.Lat,
+			Lon: wp.Lon,
+			Leg: i,
+		}
+		if i > 0 {
+			legDistance := Distance(WaypointPosition(route.Waypoints[i - 1]), WaypointPosition(wp))
+			speed, ok := options.LegSpeedsKn[i]
+			if !ok {
+				speed = options.SpeedKn
+			}
+			legSeconds := legDistance / speed * 3600
+			distance += legDistance
+			elapsed += legSeconds
+			if elapsed > maxPlanSeconds {
+				return Plan{}, fmt.Errorf("leg %d: %w", i, ErrPlanTooLong)
+			}
+
+			point.LegDistanceNm = round(legDistance, 100)
+			point.LegSpeedKn = speed
+			point.LegSeconds = int64(math.Round(legSeconds))
+		}
+		point.DistanceNm = round(distance, 100)
+		point.ElapsedSeconds = int64(math.Round(elapsed))
+		point.Eta = options.Start.Add(time.Duration(point.ElapsedSeconds) * time.Second)
+		plan.Waypoints = append(plan.Waypoints, point)
+	}
+
+	plan.TotalDistanceNm = round(distance, 100)
+	plan.TotalSeconds = int64(math.Round(elapsed))
+	plan.Finish = options.Start.Add(time.Duration(plan.TotalSeconds) * time.Second)
+	if plan.Finish.Year() > 9999 {
+		return Plan{}, fmt.Errorf("%w: finish %d is after the year 9999", ErrPlanTooLong, plan.Finish.Year())
+	}
+	return plan, nil
+}
+
+func maxInt(a int, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
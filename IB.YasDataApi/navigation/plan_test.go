@@ -0,0 +1,66 @@
+package navigation
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"IB.YasDataApi/abstract"
+)
+
+func TestPlanRoute(t *testing.T) {
+	start := time.Date(2023, 6, 17, 9, 0, 0, 0, time.UTC)
+	route := abstract.Route {
+		RouteId: 957,
+		RouteName: "Equator",
+		Waypoints: []abstract.Waypoint {
+			{ WaypointId: 1, Lat: 0, Lon: 0 },
+			{ WaypointId: 2, Lat: 0, Lon: 1 },
+			{ WaypointId: 3, Lat: 0, Lon: 3 },
+		},
+	}
+
+	plan, err := PlanRoute(route, PlanOptions{ SpeedKn: degreeNm, Start: start, LegSpeedsKn: map[int]float64{ 2: degreeNm * 4 } })
+	if err != nil {
+		t.Fatalf("plan: %v", err)
+	}
+	wantSeconds := []int64{ 0, 3600, 5400 }
+	for i, point := range plan.Waypoints {
+		if point.ElapsedSeconds != wantSeconds[i] || !point.Eta.Equal(start.Add(time.Duration(wantSeconds[i]) * time.Second)) {
+			t.Errorf("waypoint %d elapsed = %d eta %v, want %d", i, point.ElapsedSeconds, point.Eta, wantSeconds[i])
+		}
+	}
+	if want := round(3 * degreeNm, 100); plan.TotalDistanceNm != want || plan.TotalSeconds != 5400 || !plan.Finish.Equal(start.Add(90 * time.Minute)) {
+		t.Errorf("plan = %v nm %d s finish %v, want %v nm 5400 s", plan.TotalDistanceNm, plan.TotalSeconds, plan.Finish, want)
+	}
+}
+
+func TestPlanRouteErrors(t *testing.T) {
+	antipodal := abstract.Route{ Waypoints: []abstract.Waypoint{{ Lat: 10, Lon: 170 }, { Lat: -10, Lon: -10 }} }
+	tests := []struct {
+		name    string
+		options PlanOptions
+		want    error
+	}{
+		{ name: "zero speed", options: PlanOptions{ SpeedKn: 0 }, want: ErrInvalidSpeed },
+		{ name: "NaN speed", options: PlanOptions{ SpeedKn: math.NaN() }, want: ErrInvalidSpeed },
+		{ name: "infinite speed", options: PlanOptions{ SpeedKn: math.Inf(1) }, want: ErrInvalidSpeed },
+		{ name: "negative leg speed", options: PlanOptions{ SpeedKn: 5, LegSpeedsKn: map[int]float64{ 1: -1 } }, want: ErrInvalidSpeed },
+		{ name: "longer than duration", options: PlanOptions{ SpeedKn: 0.001 }, want: ErrPlanTooLong },
+		{ name: "longer than duration on the leg", options: PlanOptions{ SpeedKn: 5, LegSpeedsKn: map[int]float64{ 1: 1e-300 } }, want: ErrPlanTooLong },
+		{ name: "finish after 9999", options: PlanOptions{ SpeedKn: 1, Start: time.Date(9999, 6, 1, 0, 0, 0, 0, time.UTC) }, want: ErrPlanTooLong },
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := PlanRoute(antipodal, test.options); !errors.Is(err, test.want) {
+				t.Errorf("plan error = %v, want %v", err, test.want)
+			}
+		})
+	}
+
+	if _, err := PlanRoute(antipodal, PlanOptions{ SpeedKn: 5, LegSpeedsKn: map[int]float64{ 2: 5 } }); err == nil {
+		t.Error("plan with leg 2 of the route of 1 leg: no error")
+	}
+}
@@ -0,0 +1,60 @@
+package navigation
+
+import (
+	"math"
+)
+
+// Rhumb line (loxodrome) distance in nautical miles, the leg of the constant true course.
+// The shorter way around the antimeridian is taken
+//
+func RhumbDistance(from Position, to Position) float64 {
+	lat1, lat2 := radians(from.Lat), radians(to.Lat)
+	dLat := lat2 - lat1
+	dLon := rhumbLongitude(from, to)
+
+	// q is the east-west stretch of the mercator projection, on the east-west leg
+	// it is the cosine of the latitude
+	//
+	dPsi := mercatorDelta(lat1, lat2)
+	q := math.Cos(lat1)
+	if math.Abs(dPsi) > 1e-12 {
+		q = dLat / dPsi
+	}
+	return math.Sqrt(dLat * dLat + q * q * dLon * dLon) * EarthRadiusNm
+}
+
+// Constant true course of the rhumb line, degrees 0..360
+//
+func RhumbBearing(from Position, to Position) float64 {
+	dLon := rhumbLongitude(from, to)
+	dPsi := mercatorDelta(radians(from.Lat), radians(to.Lat))
+	return normalizeBearing(degrees(math.Atan2(dLon, dPsi)))
+}
+
+// Difference of the longitudes the shorter way around. The longitude of a pole is any,
+// the leg to or from the pole goes along the meridian
+//
+func rhumbLongitude(from Position, to Position) float64 {
+	if math.Abs(from.Lat) == 90 || math.Abs(to.Lat) == 90 {
+		return 0
+	}
+	return antimeridian(radians(to.Lon - from.Lon))
+}
+
+// Difference of the latitudes stretched by the mercator projection
+//
+func mercatorDelta(lat1 float64, lat2 float64) float64 {
+	return math.Log(math.Tan(math.Pi / 4 + lat2 / 2) / math.Tan(math.Pi / 4 + lat1 / 2))
+}
+
+// Wraps longitude difference into -pi..pi
+//
+func antimeridian(dLon float64) float64 {
+	if math.Abs(dLon) > math.Pi {
+		if dLon > 0 {
+			return dLon - 2 * math.Pi
+		}
+		return dLon + 2 * math.Pi
+	}
+	return dLon
+}
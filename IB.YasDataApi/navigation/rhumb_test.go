@@ -0,0 +1,55 @@
+package navigation
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRhumb(t *testing.T) {
+	// Plymouth 50°21'59"N 004°08'02"W to Boston 42°21'04"N 071°02'27"W is 5198 km at 260°07'38",
+	// the example of Movable Type Scripts
+	//
+	plymouth := Position{ 50 + 21.0 / 60 + 59.0 / 3600, -(4 + 8.0 / 60 + 2.0 / 3600) }
+	boston := Position{ 42 + 21.0 / 60 + 4.0 / 3600, -(71 + 2.0 / 60 + 27.0 / 3600) }
+
+	tests := []struct {
+		name     string
+		from     Position
+		to       Position
+		distance float64
+		bearing  float64
+	}{
+		{ name: "plymouth to boston", from: plymouth, to: boston, distance: 5198000 / MetersPerNm, bearing: 260 + 7.0 / 60 + 38.0 / 3600 },
+		{ name: "same position", from: boston, to: boston, distance: 0, bearing: 0 },
+		{ name: "north", from: Position{ -10, 24 }, to: Position{ 10, 24 }, distance: 20 * degreeNm, bearing: 0 },
+		{ name: "east on the equator", from: Position{ 0, 10 }, to: Position{ 0, 20 }, distance: 10 * degreeNm, bearing: 90 },
+		{ name: "east at 60N", from: Position{ 60, 0 }, to: Position{ 60, 10 }, distance: 5 * degreeNm, bearing: 90 },
+		{ name: "west at 60S", from: Position{ -60, 10 }, to: Position{ -60, 0 }, distance: 5 * degreeNm, bearing: 270 },
+		{ name: "east across the antimeridian", from: Position{ 60, 175 }, to: Position{ 60, -175 }, distance: 5 * degreeNm, bearing: 90 },
+		{ name: "west across the antimeridian", from: Position{ 0, -175 }, to: Position{ 0, 175 }, distance: 10 * degreeNm, bearing: 270 },
+		{ name: "to the pole", from: Position{ 0, 0 }, to: Position{ 90, 45 }, distance: 90 * degreeNm, bearing: 0 },
+		{ name: "from the pole", from: Position{ -90, 0 }, to: Position{ 0, 0 }, distance: 90 * degreeNm, bearing: 0 },
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := RhumbDistance(test.from, test.to); !(math.Abs(got - test.distance) < 0.5) {
+				t.Errorf("distance = %v, want %v", got, test.distance)
+			}
+			if got := RhumbBearing(test.from, test.to); !(bearingDifference(got, test.bearing) < 1e-3) {
+				t.Errorf("bearing = %v, want %v", got, test.bearing)
+			}
+		})
+	}
+}
+
+func TestRhumbIsNotShorterThanGreatCircle(t *testing.T) {
+	positions := []Position{ lax, jfk, { 0, 0 }, { 60, 175 }, { -33.85679, 151.2153 }, { 89, -30 }, { -54.80191, -68.30295 } }
+	for _, from := range positions {
+		for _, to := range positions {
+			if rhumb, greatCircle := RhumbDistance(from, to), Distance(from, to); rhumb < greatCircle - 1e-6 {
+				t.Errorf("%v to %v: rhumb %v is shorter than great circle %v", from, to, rhumb, greatCircle)
+			}
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package navigation
+
+import (
+	"math"
+	"time"
+
+	"IB.YasDataApi/abstract"
+	"IB.YasDataApi/wmm"
+)
+
+// Leg between two consecutive waypoints of the route, distances in nautical miles,
+// bearings in degrees true
+//
+type Leg struct {
+	From Position
+	To Position
+	Distance float64
+	InitialBearing float64
+	FinalBearing float64
+	RhumbDistance float64
+	RhumbBearing float64
+}
+
+func WaypointPosition(waypoint abstract.Waypoint) Position {
+	return Position{ Lat: waypoint.Lat, Lon: waypoint.Lon }
+}
+
+// Returns legs of the route in the order of the waypoints, one leg less than waypoints
+//
+func Legs(route abstract.Route) []Leg {
+	var legs []Leg
+	for i := 1; i < len(route.Waypoints); i++ {
+		from, to := WaypointPosition(route.Waypoints[i - 1]), WaypointPosition(route.Waypoints[i])
+		legs = append(legs, Leg {
+			From: from,
+			To: to,
+			Distance: Distance(from, to),
+			InitialBearing: InitialBearing(from, to),
+			FinalBearing: FinalBearing(from, to),
+			RhumbDistance: RhumbDistance(from, to),
+			RhumbBearing: RhumbBearing(from, to),
+		})
+	}
+	return legs
+}
+
+// Great-circle length of the route in nautical miles
+//
+func TotalDistance(route abstract.Route) float64 {
+	total := 0.0
+	for _, leg := range Legs(route) {
+		total += leg.Distance
+	}
+	return total
+}
+
+// Sets the total distance of the route and the great-circle leg from the previous waypoint
+// to every waypoint but the first one. Distances are rounded to 0.01 nm, bearings to 0.1 degree
+//
+func AddStatistics(route *abstract.Route) {
+	total := 0.0
+	for i, leg := range Legs(*route) {
+		total += leg.Distance
+		distance, bearing := round(leg.Distance, 100), round(leg.InitialBearing, 10)
+		if bearing == 360 {
+			bearing = 0
+		}
+		route.Waypoints[i + 1].LegDistanceNm = &distance
+		route.Waypoints[i + 1].LegBearing = &bearing
+	}
+	total = round(total, 100)
+	route.TotalDistanceNm = &total
+}
+
+// Sets the magnetic variation of the embedded World Magnetic Model at every waypoint
+// on the date and the magnetic bearing of the legs set by AddStatistics. The magnetic bearing
+// is the true bearing less the variation at the start of the leg, where the course is set.
+// Returns wmm.ErrOutOfRange if the date is outside of the model validity
+//
+func AddMagnetic(route *abstract.Route, date time.Time) error {
+	year := wmm.DecimalYear(date)
+	variations := make([]float64, len(route.Waypoints))
+	for i, wp := range route.Waypoints {
+		field, err := wmm.Default().Field(wp.Lat, wp.Lon, 0, year)
+		if err != nil {
+			return err
+		}
+		variations[i] = field.Declination
+	}
+
+	for i := range route.Waypoints {
+		wp := &route.Waypoints[i]
+		variation := round(variations[i], 10)
+		wp.MagneticVariation = &variation
+		if i > 0 && wp.LegBearing != nil {
+			bearing := round(normalizeBearing(*wp.LegBearing - variations[i - 1]), 10)
+			if bearing == 360 {
+				bearing = 0
+			}
+			wp.LegBearingMagnetic = &bearing
+		}
+	}
+	return nil
+}
+
+func round(value float64, scale float64) float64 {
+	return math.Round(value * scale) / scale
+}
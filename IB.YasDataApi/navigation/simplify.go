@@ -0,0 +1,187 @@
+package navigation
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"math"
+
+	"IB.YasDataApi/abstract"
+)
+
+var ErrTooManyPoints = errors.New("too many waypoints")
+
+// Simplification keeps at most MaxPoints waypoints and drops waypoints closer than ToleranceMeters
+// to the simplified route, either of them may be zero. Densification splits great-circle legs longer
+// than DensifyNm into equal parts. Zero options leave the waypoints as they are
+//
+type ShapeOptions struct {
+	MaxPoints int
+	ToleranceMeters float64
+	DensifyNm float64
+}
+
+func (options ShapeOptions) IsZero() bool {
+	return options == ShapeOptions{}
+}
+
+// Simplifies and then densifies the waypoints, order ids of the result are the positions.
+// Returns ErrTooManyPoints if the result has more than limit waypoints
+//
+func Reshape(waypoints []abstract.Waypoint, options ShapeOptions, limit int) ([]abstract.Waypoint, error) {
+	if options.MaxPoints > 0 || options.ToleranceMeters > 0 {
+		waypoints = Simplify(waypoints, options.MaxPoints, options.ToleranceMeters)
+	}
+	count := len(waypoints)
+	if options.DensifyNm > 0 {
+		count = densifiedCount(waypoints, options.DensifyNm)
+	}
+	if count > limit {
+		return nil, fmt.Errorf("%w: %d, at most %d", ErrTooManyPoints, count, limit)
+	}
+	if options.DensifyNm > 0 {
+		waypoints = Densify(waypoints, options.DensifyNm)
+	}
+
+	result := make([]abstract.Waypoint, len(waypoints))
+	for i, wp := range waypoints {
+		result[i] = wp
+		result[i].OrderId = int32(i)
+	}
+	return result, nil
+}
+
+// Douglas-Peucker simplification by the great-circle cross-track distance. The waypoint farthest
+// from the simplified route is added until the route has maxPoints waypoints or the farthest one
+// is within the tolerance. Zero maxPoints or tolerance does not limit. The first and the last
+// waypoints are always kept
+//
+func Simplify(waypoints []abstract.Waypoint, maxPoints int, toleranceMeters float64) []abstract.Waypoint {
+	if len(waypoints) <= 2 {
+		return waypoints
+	}
+	if maxPoints <= 0 {
+		maxPoints = len(waypoints)
+	}
+	if maxPoints < 2 {
+		maxPoints = 2
+	}
+
+	keep := make([]bool, len(waypoints))
+	keep[0], keep[len(waypoints) - 1] = true, true
+	kept := 2
+
+	segments := &segmentHeap{}
+	pushSegment(segments, waypoints, 0, len(waypoints) - 1)
+	for kept < maxPoints && segments.Len() > 0 {
+		farthest := heap.Pop(segments).(segment)
+		if farthest.deviation <= toleranceMeters {
+			break
+		}
+		keep[farthest.index] = true
+		kept++
+		pushSegment(segments, waypoints, farthest.start, farthest.index)
+		pushSegment(segments, waypoints, farthest.index, farthest.end)
+	}
+
+	result := make([]abstract.Waypoint, 0, kept)
+	for i, wp := range waypoints {
+		if keep[i] {
+			result = append(result, wp)
+		}
+	}
+	return result
+}
+
+// Inserts unnamed waypoints on the great circle so no leg is longer than maxLegNm
+//
+func Densify(waypoints []abstract.Waypoint, maxLegNm float64) []abstract.Waypoint {
+	if len(waypoints) == 0 || !(maxLegNm > 0) {
+		return waypoints
+	}
+
+	result := []abstract.Waypoint{ waypoints[0] }
+	for i := 1; i < len(waypoints); i++ {
+		from, to := WaypointPosition(waypoints[i - 1]), WaypointPosition(waypoints[i])
+		parts := int(math.Ceil(Distance(from, to) / maxLegNm))
+		for part := 1; part < parts; part++ {
+			position := Intermediate(from, to, float64(part) / float64(parts))
+			result = append(result, abstract.Waypoint{ Lat: position.Lat, Lon: position.Lon })
+		}
+		result = append(result, waypoints[i])
+	}
+	return result
+}
+
+// Number of waypoints after Densify, the densified route is not built
+//
+func densifiedCount(waypoints []abstract.Waypoint, maxLegNm float64) int {
+	count := len(waypoints)
+	for i := 1; i < len(waypoints); i++ {
+		parts := math.Ceil(Distance(WaypointPosition(waypoints[i - 1]), WaypointPosition(waypoints[i])) / maxLegNm)
+		if parts > 1 {
+			count += int(math.Min(parts - 1, math.MaxInt32))
+		}
+	}
+	return count
+}
+
+// Distance in metres from the position to the great-circle segment, the distance
+// to the nearest end if the position is not abreast of the segment
+//
+func CrossTrackDistance(position Position, start Position, end Position) float64 {
+	toPosition := Distance(start, position) / EarthRadiusNm
+	toEnd := Distance(start, end) / EarthRadiusNm
+	if toEnd == 0 {
+		return toPosition * EarthRadiusMeters
+	}
+
+	angle := radians(InitialBearing(start, position) - InitialBearing(start, end))
+	if math.Cos(angle) < 0 {
+		return toPosition * EarthRadiusMeters
+	}
+	crossTrack := math.Asin(math.Sin(toPosition) * math.Sin(angle))
+	alongTrack := math.Acos(math.Max(-1, math.Min(1, math.Cos(toPosition) / math.Cos(crossTrack))))
+	if alongTrack > toEnd {
+		return Distance(end, position) * MetersPerNm
+	}
+	return math.Abs(crossTrack) * EarthRadiusMeters
+}
+
+// Part of the route between two kept waypoints with its farthest waypoint
+//
+type segment struct {
+	start, end int
+	index int
+	deviation float64
+}
+
+func pushSegment(segments *segmentHeap, waypoints []abstract.Waypoint, start int, end int) {
+	if end - start < 2 {
+		return
+	}
+	farthest := segment{ start: start, end: end, index: -1, deviation: -1 }
+	startPosition, endPosition := WaypointPosition(waypoints[start]), WaypointPosition(waypoints[end])
+	for i := start + 1; i < end; i++ {
+		deviation := CrossTrackDistance(WaypointPosition(waypoints[i]), startPosition, endPosition)
+		if deviation > farthest.deviation {
+			farthest.index, farthest.deviation = i, deviation
+		}
+	}
+	heap.Push(segments, farthest)
+}
+
+// Max-heap of segments by the deviation of the farthest waypoint
+//
+type segmentHeap []segment
+
+func (h segmentHeap) Len() int { return len(h) }
+func (h segmentHeap) Less(i, j int) bool { return h[i].deviation > h[j].deviation }
+func (h segmentHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *segmentHeap) Push(x interface{}) { *h = append(*h, x.(segment)) }
+func (h *segmentHeap) Pop() interface{} {
+	old := *h
+	last := old[len(old) - 1]
+	*h = old[:len(old) - 1]
+	return last
+}
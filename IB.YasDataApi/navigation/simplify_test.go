@@ -0,0 +1,214 @@
+package navigation
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"IB.YasDataApi/abstract"
+)
+
+func TestSimplify(t *testing.T) {
+	// Zigzag along the equator, odd waypoints are 0.01° (~1.1 km) north of the line
+	//
+	zigzag := make([]abstract.Waypoint, 0, 21)
+	for i := 0; i <= 20; i++ {
+		zigzag = append(zigzag, abstract.Waypoint{ WaypointId: int32(i), Lat: 0.01 * float64(i % 2), Lon: 0.1 * float64(i) })
+	}
+	// Straight line with 10 m noise and a corner at waypoint 5
+	//
+	corner := []abstract.Waypoint {
+		{ WaypointId: 0, Lat: 0, Lon: 0 },
+		{ WaypointId: 1, Lat: 0.0001, Lon: 0.1 },
+		{ WaypointId: 2, Lat: -0.00005, Lon: 0.2 },
+		{ WaypointId: 3, Lat: 0, Lon: 0.3 },
+		{ WaypointId: 4, Lat: 0.00008, Lon: 0.4 },
+		{ WaypointId: 5, Lat: 0, Lon: 0.5 },
+		{ WaypointId: 6, Lat: 0.1, Lon: 0.5 },
+		{ WaypointId: 7, Lat: 0.2, Lon: 0.50007 },
+		{ WaypointId: 8, Lat: 0.3, Lon: 0.5 },
+	}
+
+	tests := []struct {
+		name      string
+		waypoints []abstract.Waypoint
+		maxPoints int
+		tolerance float64
+		want      []int32
+	}{
+		{ name: "no waypoints", waypoints: nil, maxPoints: 2, tolerance: 100, want: []int32{} },
+		{ name: "one waypoint", waypoints: zigzag[:1], maxPoints: 2, tolerance: 100, want: []int32{ 0 } },
+		{ name: "two waypoints", waypoints: zigzag[:2], maxPoints: 0, tolerance: 1e6, want: []int32{ 0, 1 } },
+		{ name: "ends only", waypoints: zigzag, maxPoints: 2, tolerance: 0, want: []int32{ 0, 20 } },
+		{ name: "max points below 2", waypoints: zigzag, maxPoints: 1, tolerance: 0, want: []int32{ 0, 20 } },
+		{ name: "tolerance above the zigzag", waypoints: zigzag, maxPoints: 0, tolerance: 1200, want: []int32{ 0, 20 } },
+		{ name: "no limits", waypoints: zigzag, maxPoints: 0, tolerance: 0, want: []int32{ 0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20 } },
+		{ name: "noise is dropped", waypoints: corner, maxPoints: 0, tolerance: 50, want: []int32{ 0, 5, 8 } },
+		{ name: "corner is the farthest", waypoints: corner, maxPoints: 3, tolerance: 0, want: []int32{ 0, 5, 8 } },
+		{ name: "noise above the tolerance", waypoints: corner, maxPoints: 0, tolerance: 10, want: []int32{ 0, 1, 2, 4, 5, 8 } },
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := Simplify(test.waypoints, test.maxPoints, test.tolerance)
+			ids := make([]int32, 0, len(got))
+			for _, wp := range got {
+				ids = append(ids, wp.WaypointId)
+			}
+			if !equalIds(ids, test.want) {
+				t.Errorf("waypoints = %v, want %v", ids, test.want)
+			}
+		})
+	}
+}
+
+func TestSimplifyKeepsEndsAndTolerance(t *testing.T) {
+	// Spiral which has no straight parts
+	//
+	var waypoints []abstract.Waypoint
+	for i := 0; i < 500; i++ {
+		angle := float64(i) * 0.05
+		radius := 0.001 * float64(i)
+		waypoints = append(waypoints, abstract.Waypoint{ WaypointId: int32(i), Lat: 59 + radius * math.Sin(angle), Lon: 24 + radius * math.Cos(angle) })
+	}
+
+	for _, tolerance := range []float64{ 10, 100, 1000, 10000 } {
+		for _, maxPoints := range []int{ 0, 3, 50 } {
+			simplified := Simplify(waypoints, maxPoints, tolerance)
+			if first, last := simplified[0], simplified[len(simplified) - 1]; first.WaypointId != 0 || last.WaypointId != 499 {
+				t.Fatalf("tolerance %v max %d: ends = %d, %d, want 0, 499", tolerance, maxPoints, first.WaypointId, last.WaypointId)
+			}
+			if maxPoints > 0 && len(simplified) > maxPoints {
+				t.Errorf("tolerance %v max %d: %d waypoints", tolerance, maxPoints, len(simplified))
+			}
+			if maxPoints > 0 && len(simplified) < maxPoints {
+				assertWithinTolerance(t, waypoints, simplified, tolerance)
+			}
+			if maxPoints == 0 {
+				assertWithinTolerance(t, waypoints, simplified, tolerance)
+			}
+		}
+	}
+}
+
+func TestDensify(t *testing.T) {
+	tests := []struct {
+		name      string
+		waypoints []abstract.Waypoint
+		maxLegNm  float64
+		count     int
+	}{
+		{ name: "no waypoints", waypoints: nil, maxLegNm: 10, count: 0 },
+		{ name: "one waypoint", waypoints: []abstract.Waypoint{{ Lat: 1, Lon: 1 }}, maxLegNm: 10, count: 1 },
+		{ name: "zero max leg", waypoints: []abstract.Waypoint{{ Lat: 0, Lon: 0 }, { Lat: 0, Lon: 10 }}, maxLegNm: 0, count: 2 },
+		{ name: "NaN max leg", waypoints: []abstract.Waypoint{{ Lat: 0, Lon: 0 }, { Lat: 0, Lon: 10 }}, maxLegNm: math.NaN(), count: 2 },
+		{ name: "short legs", waypoints: []abstract.Waypoint{{ Lat: 0, Lon: 0 }, { Lat: 0, Lon: 0.1 }, { Lat: 0, Lon: 0.1 }}, maxLegNm: 10, count: 3 },
+		{ name: "equal parts", waypoints: []abstract.Waypoint{{ Lat: 0, Lon: 0 }, { Lat: 0, Lon: 1 }}, maxLegNm: degreeNm / 3.5, count: 5 },
+		{ name: "lax to jfk", waypoints: []abstract.Waypoint{ waypoint(lax), waypoint(jfk) }, maxLegNm: 100, count: 23 },
+		{ name: "across the antimeridian", waypoints: []abstract.Waypoint{{ Lat: -17, Lon: 178 }, { Lat: -15, Lon: -178 }}, maxLegNm: 20, count: 15 },
+		{ name: "over the pole", waypoints: []abstract.Waypoint{{ Lat: 80, Lon: 0 }, { Lat: 80, Lon: 180 }}, maxLegNm: 100, count: 14 },
+		{ name: "antipodal", waypoints: []abstract.Waypoint{{ Lat: 10, Lon: 170 }, { Lat: -10, Lon: -10 }}, maxLegNm: 1000, count: 12 },
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			densified := Densify(test.waypoints, test.maxLegNm)
+			if len(densified) != test.count {
+				t.Fatalf("waypoints = %d, want %d", len(densified), test.count)
+			}
+			if test.maxLegNm > 0 && densifiedCount(test.waypoints, test.maxLegNm) != test.count {
+				t.Errorf("densified count = %d, want %d", densifiedCount(test.waypoints, test.maxLegNm), test.count)
+			}
+			if len(test.waypoints) == 0 {
+				return
+			}
+			if first, last := densified[0], densified[len(densified) - 1]; first != test.waypoints[0] || last != test.waypoints[len(test.waypoints) - 1] {
+				t.Errorf("ends = %v, %v, want %v, %v", first, last, test.waypoints[0], test.waypoints[len(test.waypoints) - 1])
+			}
+
+			total := 0.0
+			for i := 1; i < len(densified); i++ {
+				from, to := WaypointPosition(densified[i - 1]), WaypointPosition(densified[i])
+				if math.IsNaN(to.Lat) || math.IsNaN(to.Lon) {
+					t.Fatalf("waypoint %d = %v", i, to)
+				}
+				leg := Distance(from, to)
+				if test.maxLegNm > 0 && leg > test.maxLegNm + 1e-6 {
+					t.Errorf("leg %d = %v nm, want at most %v", i, leg, test.maxLegNm)
+				}
+				total += leg
+			}
+			want := 0.0
+			for i := 1; i < len(test.waypoints); i++ {
+				want += Distance(WaypointPosition(test.waypoints[i - 1]), WaypointPosition(test.waypoints[i]))
+			}
+			if math.Abs(total - want) > 1e-3 {
+				t.Errorf("densified distance = %v nm, want %v, the points are off the great circle", total, want)
+			}
+		})
+	}
+}
+
+func TestReshape(t *testing.T) {
+	waypoints := []abstract.Waypoint {
+		{ WaypointId: 1, WaypointName: "A", OrderId: 7, Lat: 0, Lon: 0 },
+		{ WaypointId: 2, WaypointName: "B", OrderId: 8, Lat: 0.00001, Lon: 0.5 },
+		{ WaypointId: 3, WaypointName: "C", OrderId: 9, Lat: 0, Lon: 1 },
+	}
+
+	reshaped, err := Reshape(waypoints, ShapeOptions{ ToleranceMeters: 100, DensifyNm: degreeNm / 2.5 }, 4)
+	if err != nil {
+		t.Fatalf("reshape: %v", err)
+	}
+	if len(reshaped) != 4 || reshaped[0].WaypointName != "A" || reshaped[3].WaypointName != "C" {
+		t.Fatalf("reshaped = %v, want A, two points, C", reshaped)
+	}
+	for i, wp := range reshaped {
+		if wp.OrderId != int32(i) {
+			t.Errorf("waypoint %d order = %d", i, wp.OrderId)
+		}
+	}
+
+	if _, err := Reshape(waypoints, ShapeOptions{ DensifyNm: 1 }, 50); !errors.Is(err, ErrTooManyPoints) {
+		t.Errorf("reshape over the limit: error = %v, want %v", err, ErrTooManyPoints)
+	}
+	if unchanged, err := Reshape(waypoints, ShapeOptions{}, 3); err != nil || len(unchanged) != 3 {
+		t.Errorf("reshape without options = %v, %v, want 3 waypoints", unchanged, err)
+	}
+}
+
+// Every dropped waypoint is within the tolerance of the simplified leg around it
+//
+func assertWithinTolerance(t *testing.T, waypoints []abstract.Waypoint, simplified []abstract.Waypoint, tolerance float64) {
+	t.Helper()
+	leg := 0
+	for _, wp := range waypoints {
+		if leg < len(simplified) - 1 && wp.WaypointId == simplified[leg + 1].WaypointId {
+			leg++
+			continue
+		}
+		if wp.WaypointId == simplified[leg].WaypointId {
+			continue
+		}
+		start, end := WaypointPosition(simplified[leg]), WaypointPosition(simplified[leg + 1])
+		if deviation := CrossTrackDistance(WaypointPosition(wp), start, end); deviation > tolerance {
+			t.Errorf("tolerance %v: waypoint %d is %v m off the simplified route", tolerance, wp.WaypointId, deviation)
+		}
+	}
+}
+
+func waypoint(position Position) abstract.Waypoint {
+	return abstract.Waypoint{ Lat: position.Lat, Lon: position.Lon }
+}
+
+func equalIds(a []int32, b []int32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
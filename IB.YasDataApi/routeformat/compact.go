@@ -0,0 +1,216 @@
+package routeformat
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+	"unicode/utf8"
+
+	"IB.YasDataApi/abstract"
+)
+
+// Compact binary route list for the watch app, version 1.
+//
+// All integers are varints as in encoding/binary: uvarint is unsigned LEB128,
+// varint is zig-zag encoded signed LEB128. Strings are one byte length followed by UTF-8 bytes.
+//
+//	header   version   byte     CompactVersion
+//	         count     uvarint  number of routes
+//	route    id        uvarint  route id
+//	         time      uvarint  upload time, unix seconds
+//	         name      string   route name, at most CompactRouteNameLength bytes
+//	         count     uvarint  number of waypoints
+//	waypoint lat       varint   latitude delta, 1e-5 degree
+//	         lon       varint   longitude delta, 1e-5 degree
+//	         name      string   waypoint name, at most CompactWaypointNameLength bytes
+//
+// Coordinates are fixed point with 1e-5 degree (~1.1 m) resolution, every coordinate is the
+// difference from the previous waypoint of the route, the first one is from 0.
+// Names are truncated on the rune boundary. User id, waypoint ids and order ids are not
+// transferred: the list belongs to one user and waypoints go in the stored order.
+// A change of the layout increments the version
+//
+const (
+	CompactVersion = 1
+	CompactRouteNameLength = 24
+	CompactWaypointNameLength = 12
+)
+
+// Degrees in one unit of the fixed point coordinate
+//
+const compactResolution = 1e-5
+
+var ErrCompactTruncated = errors.New("compact: unexpected end of data")
+
+// Encodes routes into the compact binary form
+//
+func EncodeCompact(routes []abstract.Route) []byte {
+	data := make([]byte, 0, 16 + len(routes) * 64)
+	data = append(data, CompactVersion)
+	data = binary.AppendUvarint(data, uint64(len(routes)))
+	for _, route := range routes {
+		data = binary.AppendUvarint(data, uint64(route.RouteId))
+		data = binary.AppendUvarint(data, uint64(maxInt64(route.UploadTime.Unix(), 0)))
+		data = appendCompactString(data, route.RouteName, CompactRouteNameLength)
+		data = binary.AppendUvarint(data, uint64(len(route.Waypoints)))
+
+		var lat, lon int64
+		for _, wp := range route.Waypoints {
+			wpLat, wpLon := toFixedPoint(wp.Lat), toFixedPoint(wp.Lon)
+			data = binary.AppendVarint(data, wpLat - lat)
+			data = binary.AppendVarint(data, wpLon - lon)
+			data = appendCompactString(data, wp.WaypointName, CompactWaypointNameLength)
+			lat, lon = wpLat, wpLon
+		}
+	}
+	return data
+}
+
+// Decodes routes from the compact binary form. Coordinates are rounded to the resolution
+// of the format, names are truncated and order ids are the positions of waypoints
+//
+func DecodeCompact(data []byte) ([]abstract.Route, error) {
+	reader := compactReader{ data: data }
+	version := reader.byte()
+	if reader.err == nil && version != CompactVersion {
+		return nil, fmt.Errorf("compact: unsupported version %d", version)
+	}
+
+	count := reader.count()
+	routes := make([]abstract.Route, 0, count)
+	for r := 0; r < count && reader.err == nil; r++ {
+		route := abstract.Route {
+			RouteId: int32(reader.uvarint()),
+			UploadTime: time.Unix(int64(reader.uvarint()), 0).UTC(),
+			RouteName: reader.string(),
+		}
+		waypoints := reader.count()
+		var lat, lon int64
+		for i := 0; i < waypoints && reader.err == nil; i++ {
+			lat += reader.varint()
+			lon += reader.varint()
+			route.Waypoints = append(route.Waypoints, abstract.Waypoint {
+				WaypointName: reader.string(),
+				Lat: fromFixedPoint(lat),
+				Lon: fromFixedPoint(lon),
+				OrderId: int32(i),
+			})
+		}
+		routes = append(routes, route)
+	}
+
+	if reader.err != nil {
+		return nil, reader.err
+	}
+	if reader.offset != len(data) {
+		return nil, fmt.Errorf("compact: %d bytes after the last route", len(data) - reader.offset)
+	}
+	return routes, nil
+}
+
+func toFixedPoint(degrees float64) int64 {
+	return int64(math.Round(degrees / compactResolution))
+}
+
+func fromFixedPoint(value int64) float64 {
+	return float64(value) * compactResolution
+}
+
+func appendCompactString(data []byte, value string, length int) []byte {
+	value = truncateUtf8(value, length)
+	data = append(data, byte(len(value)))
+	return append(data, value...)
+}
+
+// Truncates string to at most length bytes without splitting a rune
+//
+func truncateUtf8(value string, length int) string {
+	if len(value) <= length {
+		return value
+	}
+	for length > 0 && !utf8.RuneStart(value[length]) {
+		length--
+	}
+	return value[:length]
+}
+
+func maxInt64(a int64, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// Sequential reader of the compact form, the first error stops reading
+//
+type compactReader struct {
+	data []byte
+	offset int
+	err error
+}
+
+func (reader *compactReader) byte() byte {
+	if reader.err != nil {
+		return 0
+	}
+	if reader.offset >= len(reader.data) {
+		reader.err = ErrCompactTruncated
+		return 0
+	}
+	value := reader.data[reader.offset]
+	reader.offset++
+	return value
+}
+
+func (reader *compactReader) uvarint() uint64 {
+	if reader.err != nil {
+		return 0
+	}
+	value, n := binary.Uvarint(reader.data[reader.offset:])
+	if n <= 0 {
+		reader.err = ErrCompactTruncated
+		return 0
+	}
+	reader.offset += n
+	return value
+}
+
+func (reader *compactReader) varint() int64 {
+	if reader.err != nil {
+		return 0
+	}
+	value, n := binary.Varint(reader.data[reader.offset:])
+	if n <= 0 {
+		reader.err = ErrCompactTruncated
+		return 0
+	}
+	reader.offset += n
+	return value
+}
+
+// Reads number of items, every item takes at least one byte so the count is limited by the data left
+//
+func (reader *compactReader) count() int {
+	value := reader.uvarint()
+	if reader.err == nil && value > uint64(len(reader.data) - reader.offset) {
+		reader.err = fmt.Errorf("compact: count %d exceeds the data", value)
+		return 0
+	}
+	return int(value)
+}
+
+func (reader *compactReader) string() string {
+	length := int(reader.byte())
+	if reader.err != nil {
+		return ""
+	}
+	if reader.offset + length > len(reader.data) {
+		reader.err = ErrCompactTruncated
+		return ""
+	}
+	value := string(reader.data[reader.offset:reader.offset + length])
+	reader.offset += length
+	return value
+}
@@ -0,0 +1,181 @@
+package routeformat
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"IB.YasDataApi/abstract"
+)
+
+func TestCompactRoundTrip(t *testing.T) {
+	uploadTime := time.Date(2023, 6, 17, 9, 30, 15, 0, time.UTC)
+	tests := []struct {
+		name   string
+		routes []abstract.Route
+	}{
+		{ name: "empty list", routes: []abstract.Route{} },
+		{ name: "route without waypoints", routes: []abstract.Route{{ RouteId: 1, RouteName: "Empty", UploadTime: uploadTime }} },
+		{
+			name: "all hemispheres",
+			routes: []abstract.Route{{
+				RouteId: 957,
+				RouteName: "Monk-Trinity",
+				UploadTime: uploadTime,
+				Waypoints: []abstract.Waypoint {
+					{ WaypointName: "Start", Lat: 59.43701, Lon: 24.75362 },
+					{ WaypointName: "South", Lat: -33.85679, Lon: 151.21530 },
+					{ WaypointName: "West", Lat: 40.68925, Lon: -74.04450 },
+					{ WaypointName: "", Lat: -54.80191, Lon: -68.30295 },
+				},
+			}},
+		},
+		{
+			name: "antimeridian and poles",
+			routes: []abstract.Route{{
+				RouteId: 2,
+				UploadTime: uploadTime,
+				Waypoints: []abstract.Waypoint {
+					{ Lat: 90, Lon: 180 },
+					{ Lat: -90, Lon: -180 },
+					{ Lat: 0, Lon: 179.99999 },
+				},
+			}},
+		},
+		{
+			name: "several routes",
+			routes: []abstract.Route {
+				{ RouteId: 10, RouteName: "A", UploadTime: uploadTime, Waypoints: []abstract.Waypoint{{ WaypointName: "A1", Lat: 1, Lon: 2 }} },
+				{ RouteId: 11, RouteName: "B", UploadTime: uploadTime.Add(time.Hour), Waypoints: []abstract.Waypoint{{ WaypointName: "B1", Lat: 3, Lon: 4 }} },
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			decoded, err := DecodeCompact(EncodeCompact(test.routes))
+			if err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			assertRoutesEqual(t, test.routes, decoded)
+		})
+	}
+}
+
+func TestCompactTruncatesNames(t *testing.T) {
+	routes := []abstract.Route{{
+		RouteId: 1,
+		RouteName: "Tallinn - Helsinki - Stockholm - Mariehamn",
+		Waypoints: []abstract.Waypoint{{ WaypointName: "Põhja-Eesti väike laht", Lat: 1, Lon: 1 }},
+	}}
+
+	decoded, err := DecodeCompact(EncodeCompact(routes))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got, want := decoded[0].RouteName, "Tallinn - Helsinki - Sto"; got != want {
+		t.Errorf("route name = %q, want %q", got, want)
+	}
+	// "Põhja-Eesti" is 12 bytes, "õ" takes two of them
+	if got, want := decoded[0].Waypoints[0].WaypointName, "Põhja-Eesti"; got != want {
+		t.Errorf("waypoint name = %q, want %q", got, want)
+	}
+}
+
+func TestCompactRoundsCoordinates(t *testing.T) {
+	routes := []abstract.Route{{ Waypoints: []abstract.Waypoint{{ Lat: 59.123456789, Lon: -24.000004999 }} }}
+
+	decoded, err := DecodeCompact(EncodeCompact(routes))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got := decoded[0].Waypoints[0]; math.Abs(got.Lat - 59.12346) > 1e-9 || math.Abs(got.Lon + 24.0) > 1e-9 {
+		t.Errorf("coordinates = %v, %v, want 59.12346, -24", got.Lat, got.Lon)
+	}
+}
+
+func TestCompactIsSmallerThanJson(t *testing.T) {
+	route := abstract.Route{ RouteId: 957, RouteName: "Monk-Trinity" }
+	for i := 0; i < 20; i++ {
+		route.Waypoints = append(route.Waypoints, abstract.Waypoint {
+			WaypointName: "WP",
+			Lat: 59.4 + float64(i) * 0.01,
+			Lon: 24.7 + float64(i) * 0.01,
+		})
+	}
+
+	// 1 version, 1 count, 2 id, 1 time, 13 name, 1 count, the first waypoint of 4 + 4 + 3 bytes
+	// and the rest of 2 + 2 + 3 bytes
+	size := len(EncodeCompact([]abstract.Route{ route }))
+	if want := 19 + 11 + 19 * 7; size != want {
+		t.Errorf("encoded size = %d, want %d", size, want)
+	}
+
+	jsonData, err := json.Marshal([]abstract.Route{ route })
+	if err != nil {
+		t.Fatalf("json: %v", err)
+	}
+	if size * 5 > len(jsonData) {
+		t.Errorf("encoded size = %d, want at least 5 times less than json %d", size, len(jsonData))
+	}
+}
+
+func TestDecodeCompactErrors(t *testing.T) {
+	valid := EncodeCompact([]abstract.Route{{
+		RouteId: 1,
+		RouteName: "Route",
+		Waypoints: []abstract.Waypoint{{ WaypointName: "WP", Lat: 1, Lon: 1 }},
+	}})
+
+	for length := 0; length < len(valid); length++ {
+		if _, err := DecodeCompact(valid[:length]); err == nil {
+			t.Errorf("decode of %d bytes of %d: no error", length, len(valid))
+		}
+	}
+
+	if _, err := DecodeCompact(valid[:len(valid) - 1]); !errors.Is(err, ErrCompactTruncated) {
+		t.Errorf("decode of truncated data: error = %v, want %v", err, ErrCompactTruncated)
+	}
+
+	unknownVersion := append([]byte{ CompactVersion + 1 }, valid[1:]...)
+	if _, err := DecodeCompact(unknownVersion); err == nil {
+		t.Error("decode of unknown version: no error")
+	}
+
+	if _, err := DecodeCompact(append(valid, 0)); err == nil {
+		t.Error("decode with trailing bytes: no error")
+	}
+
+	if _, err := DecodeCompact([]byte{ CompactVersion, 0xff, 0xff, 0xff, 0x0f }); err == nil {
+		t.Error("decode of huge route count: no error")
+	}
+}
+
+func assertRoutesEqual(t *testing.T, want []abstract.Route, got []abstract.Route) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("routes = %d, want %d", len(got), len(want))
+	}
+	for r := range want {
+		if got[r].RouteId != want[r].RouteId || got[r].RouteName != want[r].RouteName {
+			t.Errorf("route %d = %d %q, want %d %q", r, got[r].RouteId, got[r].RouteName, want[r].RouteId, want[r].RouteName)
+		}
+		if !got[r].UploadTime.Equal(want[r].UploadTime) && !(want[r].UploadTime.IsZero() && got[r].UploadTime.Unix() == 0) {
+			t.Errorf("route %d upload time = %v, want %v", r, got[r].UploadTime, want[r].UploadTime)
+		}
+		if len(got[r].Waypoints) != len(want[r].Waypoints) {
+			t.Fatalf("route %d waypoints = %d, want %d", r, len(got[r].Waypoints), len(want[r].Waypoints))
+		}
+		for i, wp := range want[r].Waypoints {
+			gotWp := got[r].Waypoints[i]
+			if gotWp.WaypointName != wp.WaypointName || gotWp.OrderId != int32(i) {
+				t.Errorf("route %d waypoint %d = %q order %d, want %q order %d", r, i, gotWp.WaypointName, gotWp.OrderId, wp.WaypointName, i)
+			}
+			if math.Abs(gotWp.Lat - wp.Lat) > compactResolution / 2 || math.Abs(gotWp.Lon - wp.Lon) > compactResolution / 2 {
+				t.Errorf("route %d waypoint %d = %v, %v, want %v, %v", r, i, gotWp.Lat, gotWp.Lon, wp.Lat, wp.Lon)
+			}
+		}
+	}
+}
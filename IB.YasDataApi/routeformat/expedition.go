@@ -0,0 +1,165 @@
+package routeformat
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"IB.YasDataApi/abstract"
+)
+
+// Column names of the Expedition marks and route export, matched case-insensitive
+//
+var expeditionColumns = map[string]string {
+	"name": "name",
+	"mark": "name",
+	"lat": "lat",
+	"latitude": "lat",
+	"lon": "lon",
+	"long": "lon",
+	"longitude": "lon",
+	"route": "route",
+}
+
+// Parses Expedition marks CSV into the route keeping the order and names of the marks.
+// Columns are taken from the header row if there is one, otherwise the order is Name, Lat, Lon.
+// Coordinates are decimal degrees or degrees and minutes with a hemisphere, e.g. "50 47.123N".
+// If the file has a Route column only the marks of the first route are taken and the route is
+// named after it. Lines starting with ! or # are comments
+//
+func ParseExpedition(r io.Reader) (abstract.Route, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.LazyQuotes = true
+
+	columns := map[string]int{ "name": 0, "lat": 1, "lon": 2, "route": -1 }
+	var route abstract.Route
+	headerChecked := false
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return abstract.Route{}, fmt.Errorf("expedition: malformed csv: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+		if len(record) == 0 || isCsvComment(record[0]) || isBlank(record) {
+			continue
+		}
+
+		if !headerChecked {
+			headerChecked = true
+			if header, ok := expeditionHeader(record); ok {
+				columns = header
+				continue
+			}
+		}
+
+		if columns["route"] >= 0 {
+			routeName := strings.TrimSpace(field(record, columns["route"]))
+			if route.RouteName == "" {
+				route.RouteName = routeName
+			}
+			if routeName != route.RouteName {
+				continue
+			}
+		}
+
+		lat, err := parseDegrees("lat", field(record, columns["lat"]), 90, "N", "S")
+		if err != nil {
+			return abstract.Route{}, fmt.Errorf("expedition: line %d: %w", line, err)
+		}
+		lon, err := parseDegrees("lon", field(record, columns["lon"]), 180, "E", "W")
+		if err != nil {
+			return abstract.Route{}, fmt.Errorf("expedition: line %d: %w", line, err)
+		}
+		route.Waypoints = append(route.Waypoints, abstract.Waypoint {
+			WaypointName: strings.TrimSpace(field(record, columns["name"])),
+			Lat: lat,
+			Lon: lon,
+		})
+	}
+
+	if err := validate(&route); err != nil {
+		return abstract.Route{}, fmt.Errorf("expedition: %w", err)
+	}
+	return route, nil
+}
+
+// Returns column indexes if the record is a header, the header must have lat and lon columns
+//
+func expeditionHeader(record []string) (map[string]int, bool) {
+	columns := map[string]int{ "name": -1, "lat": -1, "lon": -1, "route": -1 }
+	for i, value := range record {
+		if column, ok := expeditionColumns[strings.ToLower(strings.TrimSpace(value))]; ok && columns[column] < 0 {
+			columns[column] = i
+		}
+	}
+	return columns, columns["lat"] >= 0 && columns["lon"] >= 0
+}
+
+// Parses coordinate in decimal degrees, degrees and minutes or degrees, minutes and seconds.
+// Negative values and the negative hemisphere letter are south and west
+//
+func parseDegrees(name string, value string, limit float64, positive string, negative string) (float64, error) {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	if value == "" {
+		return 0, fmt.Errorf("%s is missing", name)
+	}
+
+	sign := 1.0
+	for _, hemisphere := range []string{ positive, negative } {
+		if strings.HasPrefix(value, hemisphere) || strings.HasSuffix(value, hemisphere) {
+			value = strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(value, hemisphere), hemisphere))
+			if hemisphere == negative {
+				sign = -1
+			}
+			break
+		}
+	}
+	if strings.HasPrefix(value, "-") {
+		sign = -1
+		value = strings.TrimPrefix(value, "-")
+	}
+
+	parts := strings.Fields(strings.NewReplacer("°", " ", "'", " ", "′", " ", "\"", " ", "″", " ").Replace(value))
+	if len(parts) == 0 || len(parts) > 3 {
+		return 0, fmt.Errorf("%s %q is not a coordinate", name, value)
+	}
+	degrees := 0.0
+	for i, part := range parts {
+		number, err := strconv.ParseFloat(part, 64)
+		if err != nil || number < 0 || math.IsInf(number, 0) || math.IsNaN(number) || (i > 0 && number >= 60) {
+			return 0, fmt.Errorf("%s %q is not a coordinate", name, value)
+		}
+		degrees += number / math.Pow(60, float64(i))
+	}
+
+	return checkCoordinate(name, sign * degrees, limit)
+}
+
+func field(record []string, index int) string {
+	if index < 0 || index >= len(record) {
+		return ""
+	}
+	return record[index]
+}
+
+func isCsvComment(value string) bool {
+	value = strings.TrimSpace(value)
+	return strings.HasPrefix(value, "!") || strings.HasPrefix(value, "#")
+}
+
+func isBlank(record []string) bool {
+	for _, value := range record {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,102 @@
+package routeformat
+
+import (
+	"strings"
+	"testing"
+
+	"IB.YasDataApi/abstract"
+)
+
+func TestParseExpedition(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  abstract.Route
+	}{
+		{
+			name: "no header",
+			input: "Start,59.44417,24.76528\nFinish,-33.85679,151.2153\n",
+			want: abstract.Route {
+				Waypoints: []abstract.Waypoint {
+					{ WaypointName: "Start", Lat: 59.44417, Lon: 24.76528 },
+					{ WaypointName: "Finish", Lat: -33.85679, Lon: 151.2153 },
+				},
+			},
+		},
+		{
+			name: "header in any order with comments",
+			input: "! Expedition marks\r\n" +
+				"Lon, Lat, Mark\r\n" +
+				"\r\n" +
+				"# comment\r\n" +
+				"24 45.9168E, 59 26.6502N, Tallinn\r\n" +
+				"W068 18.177, S54 48.1146, \"Ushuaia, AR\"\r\n",
+			want: abstract.Route {
+				Waypoints: []abstract.Waypoint {
+					{ WaypointName: "Tallinn", Lat: 59.44417, Lon: 24.76528 },
+					{ WaypointName: "Ushuaia, AR", Lat: -54.80191, Lon: -68.30295 },
+				},
+			},
+		},
+		{
+			name: "degrees minutes and seconds",
+			input: "Name,Latitude,Longitude\nA,\"59°26'39.012\"\"N\",\"-24°45'55.008\"\nB,-90,180\n",
+			want: abstract.Route {
+				Waypoints: []abstract.Waypoint {
+					{ WaypointName: "A", Lat: 59.44417, Lon: -24.76528 },
+					{ WaypointName: "B", Lat: -90, Lon: 180 },
+				},
+			},
+		},
+		{
+			name: "first route only",
+			input: "Route,Name,Lat,Lon\nRace 1,A,1,2\nRace 2,X,9,9\nRace 1,B,3,4\n",
+			want: abstract.Route {
+				RouteName: "Race 1",
+				Waypoints: []abstract.Waypoint {
+					{ WaypointName: "A", Lat: 1, Lon: 2 },
+					{ WaypointName: "B", Lat: 3, Lon: 4 },
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			route, err := ParseExpedition(strings.NewReader(test.input))
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			assertRoutesEqual(t, []abstract.Route{ test.want }, []abstract.Route{ route })
+		})
+	}
+}
+
+func TestParseExpeditionErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{ name: "empty", input: "", want: ErrNoWaypoints.Error() },
+		{ name: "header only", input: "Name,Lat,Lon\n", want: ErrNoWaypoints.Error() },
+		{ name: "latitude is text", input: "A,1,2\nB,abc,2\n", want: `expedition: line 2: lat "ABC" is not a coordinate` },
+		{ name: "longitude is missing", input: "Name,Lat,Lon\nA,1\n", want: "expedition: line 2: lon is missing" },
+		{ name: "minutes out of range", input: "A,59 60.5N,24\n", want: `expedition: line 1: lat "59 60.5" is not a coordinate` },
+		{ name: "latitude out of range", input: "A,91,24\n", want: "expedition: line 1: lat 91 is out of range" },
+		{ name: "longitude out of range", input: "A,1,180 30W\n", want: "expedition: line 1: lon -180.5 is out of range" },
+		{ name: "too many parts", input: "A,1 2 3 4,2\n", want: `expedition: line 1: lat "1 2 3 4" is not a coordinate` },
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ParseExpedition(strings.NewReader(test.input))
+			if err == nil {
+				t.Fatalf("parse: no error, want %q", test.want)
+			}
+			if !strings.Contains(err.Error(), test.want) {
+				t.Errorf("error = %q, want %q", err, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package routeformat
+
+import (
+	"IB.YasDataApi/abstract"
+)
+
+// Value of the featureType property
+//
+const (
+	GeoJsonRouteFeature = "route"
+	GeoJsonWaypointFeature = "waypoint"
+)
+
+// RFC 7946 feature collection
+//
+type FeatureCollection struct {
+	Type     string    `json:"type"`
+	Features []Feature `json:"features"`
+}
+
+type Feature struct {
+	Type       string                 `json:"type"`
+	Geometry   *Geometry              `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// Point has one position in Coordinates, LineString has a position per vertex.
+// Position is [lon, lat]
+//
+type Geometry struct {
+	Type        string      `json:"type"`
+	Coordinates interface{} `json:"coordinates"`
+}
+
+// Converts routes into feature collection: LineString feature per route followed by Point features
+// of its waypoints. Properties mirror the JSON fields of abstract.Route and abstract.Waypoint,
+// routes with less than two waypoints have null geometry
+//
+func ToGeoJson(routes []abstract.Route) FeatureCollection {
+	collection := FeatureCollection{ Type: "FeatureCollection", Features: []Feature{} }
+	for _, route := range routes {
+		line := make([][]float64, 0, len(route.Waypoints))
+		for _, wp := range route.Waypoints {
+			line = append(line, []float64{ wp.Lon, wp.Lat })
+		}
+
+		routeFeature := Feature {
+			Type: "Feature",
+			Properties: map[string]interface{} {
+				"featureType": GeoJsonRouteFeature,
+				"routeId": route.RouteId,
+				"userId": route.UserId,
+				"routeName": route.RouteName,
+				"routeDate": route.UploadTime,
+			},
+		}
+		if len(line) > 1 {
+			routeFeature.Geometry = &Geometry{ Type: "LineString", Coordinates: line }
+		}
+		collection.Features = append(collection.Features, routeFeature)
+
+		for i, wp := range route.Waypoints {
+			collection.Features = append(collection.Features, Feature {
+				Type: "Feature",
+				Geometry: &Geometry{ Type: "Point", Coordinates: line[i] },
+				Properties: map[string]interface{} {
+					"featureType": GeoJsonWaypointFeature,
+					"routeId": route.RouteId,
+					"waypointId": wp.WaypointId,
+					"waypointName": wp.WaypointName,
+					"orderId": wp.OrderId,
+				},
+			})
+		}
+	}
+	return collection
+}
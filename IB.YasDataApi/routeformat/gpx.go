@@ -0,0 +1,165 @@
+package routeformat
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"IB.YasDataApi/abstract"
+)
+
+// GPX 1.1 document, only the elements stored in the route are mapped.
+// Elements are matched by the local name, so GPX 1.0 files are accepted as well
+//
+type gpxDocument struct {
+	XMLName   xml.Name   `xml:"gpx"`
+	Waypoints []gpxPoint `xml:"wpt"`
+	Routes    []gpxRoute `xml:"rte"`
+}
+
+type gpxRoute struct {
+	Name   string     `xml:"name"`
+	Points []gpxPoint `xml:"rtept"`
+}
+
+type gpxPoint struct {
+	Lat  string `xml:"lat,attr"`
+	Lon  string `xml:"lon,attr"`
+	Name string `xml:"name"`
+}
+
+// Parses GPX document into the route. Points of <rte> elements go first followed by <wpt> elements,
+// the route name is taken from the first <rte>, the same way the telegram bot does.
+// Returned route has no name if the document has no named <rte>
+//
+func ParseGpx(r io.Reader) (abstract.Route, error) {
+	var doc gpxDocument
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return abstract.Route{}, fmt.Errorf("gpx: malformed document: %w", err)
+	}
+
+	var route abstract.Route
+	if len(doc.Routes) > 0 {
+		route.RouteName = doc.Routes[0].Name
+	}
+	for i, rte := range doc.Routes {
+		for j, point := range rte.Points {
+			waypoint, err := point.waypoint()
+			if err != nil {
+				return abstract.Route{}, fmt.Errorf("gpx: rte %d, rtept %d: %w", i + 1, j + 1, err)
+			}
+			route.Waypoints = append(route.Waypoints, waypoint)
+		}
+	}
+	for i, point := range doc.Waypoints {
+		waypoint, err := point.waypoint()
+		if err != nil {
+			return abstract.Route{}, fmt.Errorf("gpx: wpt %d: %w", i + 1, err)
+		}
+		route.Waypoints = append(route.Waypoints, waypoint)
+	}
+
+	if err := validate(&route); err != nil {
+		return abstract.Route{}, fmt.Errorf("gpx: %w", err)
+	}
+	return route, nil
+}
+
+func (point gpxPoint) waypoint() (abstract.Waypoint, error) {
+	lat, lon, err := parseCoordinates(point.Lat, point.Lon)
+	if err != nil {
+		return abstract.Waypoint{}, err
+	}
+	return abstract.Waypoint {
+		WaypointName: strings.TrimSpace(point.Name),
+		Lat: lat,
+		Lon: lon,
+	}, nil
+}
+
+const (
+	gpxNamespace = "http://www.topografix.com/GPX/1/1"
+	gpxSchemaLocation = "http://www.topografix.com/GPX/1/1 http://www.topografix.com/GPX/1/1/gpx.xsd"
+	xsiNamespace = "http://www.w3.org/2001/XMLSchema-instance"
+)
+
+type gpxOutDocument struct {
+	XMLName        xml.Name       `xml:"gpx"`
+	Version        string         `xml:"version,attr"`
+	Creator        string         `xml:"creator,attr"`
+	Xmlns          string         `xml:"xmlns,attr"`
+	XmlnsXsi       string         `xml:"xmlns:xsi,attr"`
+	SchemaLocation string         `xml:"xsi:schemaLocation,attr"`
+	Metadata       gpxOutMetadata `xml:"metadata"`
+	Routes         []gpxOutRoute  `xml:"rte"`
+}
+
+type gpxOutMetadata struct {
+	Name string `xml:"name,omitempty"`
+	Time string `xml:"time,omitempty"`
+}
+
+type gpxOutRoute struct {
+	Name   string        `xml:"name"`
+	Desc   string        `xml:"desc,omitempty"`
+	Points []gpxOutPoint `xml:"rtept"`
+}
+
+type gpxOutPoint struct {
+	Lat  string `xml:"lat,attr"`
+	Lon  string `xml:"lon,attr"`
+	Name string `xml:"name,omitempty"`
+}
+
+// Writes routes as GPX 1.1 document, one <rte> per route with waypoints in the stored order.
+// Metadata carries the document name and the latest upload time, every <rte> has its upload time in <desc>
+//
+func WriteGpx(w io.Writer, name string, routes []abstract.Route) error {
+	doc := gpxOutDocument {
+		Version: "1.1",
+		Creator: generator,
+		Xmlns: gpxNamespace,
+		XmlnsXsi: xsiNamespace,
+		SchemaLocation: gpxSchemaLocation,
+		Metadata: gpxOutMetadata { Name: name },
+		Routes: make([]gpxOutRoute, 0, len(routes)),
+	}
+
+	var lastUpload time.Time
+	for _, route := range routes {
+		rte := gpxOutRoute {
+			Name: route.RouteName,
+			Points: make([]gpxOutPoint, 0, len(route.Waypoints)),
+		}
+		if !route.UploadTime.IsZero() {
+			rte.Desc = "Uploaded " + formatTime(route.UploadTime)
+			if route.UploadTime.After(lastUpload) {
+				lastUpload = route.UploadTime
+			}
+		}
+		for _, wp := range route.Waypoints {
+			rte.Points = append(rte.Points, gpxOutPoint {
+				Lat: formatCoordinate(wp.Lat),
+				Lon: formatCoordinate(wp.Lon),
+				Name: wp.WaypointName,
+			})
+		}
+		doc.Routes = append(doc.Routes, rte)
+	}
+	if !lastUpload.IsZero() {
+		doc.Metadata.Time = formatTime(lastUpload)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,133 @@
+package routeformat
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"IB.YasDataApi/abstract"
+)
+
+func TestParseGpx(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  abstract.Route
+	}{
+		{
+			name: "route",
+			input: `<?xml version="1.0" encoding="UTF-8"?>
+<gpx version="1.1" creator="test" xmlns="http://www.topografix.com/GPX/1/1">
+  <rte>
+    <name> Tallinn - Helsinki </name>
+    <rtept lat="59.44417" lon="24.76528"><name>Tallinn</name></rtept>
+    <rtept lat="60.16952" lon="24.93545"><name> Helsinki </name></rtept>
+  </rte>
+</gpx>`,
+			want: abstract.Route {
+				RouteName: "Tallinn - Helsinki",
+				Waypoints: []abstract.Waypoint {
+					{ WaypointName: "Tallinn", Lat: 59.44417, Lon: 24.76528 },
+					{ WaypointName: "Helsinki", Lat: 60.16952, Lon: 24.93545 },
+				},
+			},
+		},
+		{
+			name: "routes first then waypoints",
+			input: `<gpx><wpt lat="-33.85679" lon="151.2153"><name>Sydney</name></wpt>` +
+				`<rte><name>First</name><rtept lat="1" lon="2"/></rte>` +
+				`<rte><name>Second</name><rtept lat="3" lon="-4"/></rte></gpx>`,
+			want: abstract.Route {
+				RouteName: "First",
+				Waypoints: []abstract.Waypoint {
+					{ Lat: 1, Lon: 2 },
+					{ Lat: 3, Lon: -4 },
+					{ WaypointName: "Sydney", Lat: -33.85679, Lon: 151.2153 },
+				},
+			},
+		},
+		{
+			name: "gpx 1.0 waypoints only",
+			input: `<gpx version="1.0" xmlns="http://www.topografix.com/GPX/1/0">` +
+				`<wpt lat="90" lon="180"><name>Pole</name></wpt><wpt lat="-90" lon="-180"/></gpx>`,
+			want: abstract.Route {
+				Waypoints: []abstract.Waypoint {
+					{ WaypointName: "Pole", Lat: 90, Lon: 180 },
+					{ Lat: -90, Lon: -180 },
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			route, err := ParseGpx(strings.NewReader(test.input))
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			assertRoutesEqual(t, []abstract.Route{ test.want }, []abstract.Route{ route })
+		})
+	}
+}
+
+func TestParseGpxErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{ name: "empty", input: "", want: "gpx: malformed document" },
+		{ name: "truncated", input: `<gpx><rte><rtept lat="1" lon="2">`, want: "gpx: malformed document" },
+		{ name: "not gpx", input: `<kml></kml>`, want: "gpx: malformed document" },
+		{ name: "no points", input: `<gpx><rte><name>Empty</name></rte></gpx>`, want: ErrNoWaypoints.Error() },
+		{ name: "missing lat", input: `<gpx><rte><rtept lon="2"/></rte></gpx>`, want: "gpx: rte 1, rtept 1: lat is missing" },
+		{ name: "lon not a number", input: `<gpx><rte><rtept lat="1" lon="2"/><rtept lat="1" lon="east"/></rte></gpx>`, want: `gpx: rte 1, rtept 2: lon "east" is not a number` },
+		{ name: "lat out of range", input: `<gpx><wpt lat="90.5" lon="0"/></gpx>`, want: "gpx: wpt 1: lat 90.5 is out of range" },
+		{ name: "lon is NaN", input: `<gpx><wpt lat="0" lon="NaN"/></gpx>`, want: `gpx: wpt 1: lon "NaN" is not a number` },
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ParseGpx(strings.NewReader(test.input))
+			if err == nil {
+				t.Fatalf("parse: no error, want %q", test.want)
+			}
+			if !strings.Contains(err.Error(), test.want) {
+				t.Errorf("error = %q, want %q", err, test.want)
+			}
+		})
+	}
+}
+
+func TestParseGpxTooManyWaypoints(t *testing.T) {
+	var input strings.Builder
+	input.WriteString("<gpx>")
+	for i := 0; i <= MaxWaypoints; i++ {
+		input.WriteString(`<wpt lat="1" lon="1"/>`)
+	}
+	input.WriteString("</gpx>")
+
+	if _, err := ParseGpx(strings.NewReader(input.String())); err == nil || errors.Is(err, ErrNoWaypoints) {
+		t.Errorf("parse of %d waypoints: error = %v, want the limit error", MaxWaypoints + 1, err)
+	}
+}
+
+func TestWriteGpxRoundTrip(t *testing.T) {
+	routes := []abstract.Route{{
+		RouteName: "Monk & Trinity",
+		Waypoints: []abstract.Waypoint {
+			{ WaypointName: "<Start>", Lat: 59.43701, Lon: 24.75362 },
+			{ Lat: -54.80191, Lon: -68.30295 },
+		},
+	}}
+
+	var output strings.Builder
+	if err := WriteGpx(&output, "Export", routes); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	route, err := ParseGpx(strings.NewReader(output.String()))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	assertRoutesEqual(t, routes, []abstract.Route{ route })
+}
@@ -0,0 +1,366 @@
+package routeformat
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"strings"
+
+	"IB.YasDataApi/abstract"
+)
+
+const kmlNamespace = "http://www.opengis.net/kml/2.2"
+
+// Upper limit of the uncompressed KML document inside KMZ archive
+//
+const maxKmlSize = 20 << 20
+
+// Points of LineString and Point placemarks closer than this (degrees) are the same point
+//
+const sameCoordinateTolerance = 1e-7
+
+type kmlPlacemark struct {
+	Name          string          `xml:"name"`
+	Point         *kmlGeometry    `xml:"Point"`
+	LineString    *kmlGeometry    `xml:"LineString"`
+	MultiGeometry *kmlMultiGeometry `xml:"MultiGeometry"`
+}
+
+type kmlMultiGeometry struct {
+	Points      []kmlGeometry `xml:"Point"`
+	LineStrings []kmlGeometry `xml:"LineString"`
+}
+
+type kmlGeometry struct {
+	Coordinates string `xml:"coordinates"`
+}
+
+// Parses KML document into the route. Vertices of LineString placemarks go first followed by
+// Point placemarks. A Point placemark at the vertex of the line gives its name to the vertex,
+// so a path with marks drawn in Google Earth becomes one route with named waypoints.
+// The route name is taken from the first LineString placemark or from the document
+//
+func ParseKml(r io.Reader) (abstract.Route, error) {
+	route, err := parseKml(r)
+	if err != nil {
+		return abstract.Route{}, fmt.Errorf("kml: %w", err)
+	}
+	return route, nil
+}
+
+// Parses KMZ archive, the route is read from doc.kml or from the first KML file in the archive
+//
+func ParseKmz(data []byte) (abstract.Route, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return abstract.Route{}, fmt.Errorf("kmz: malformed archive: %w", err)
+	}
+
+	var kmlFile *zip.File
+	for _, file := range archive.File {
+		if !strings.EqualFold(path.Ext(file.Name), ".kml") {
+			continue
+		}
+		if kmlFile == nil || strings.EqualFold(file.Name, "doc.kml") {
+			kmlFile = file
+		}
+	}
+	if kmlFile == nil {
+		return abstract.Route{}, errors.New("kmz: archive has no KML document")
+	}
+
+	reader, err := kmlFile.Open()
+	if err != nil {
+		return abstract.Route{}, fmt.Errorf("kmz: %w", err)
+	}
+	defer reader.Close()
+
+	route, err := parseKml(io.LimitReader(reader, maxKmlSize))
+	if err != nil {
+		return abstract.Route{}, fmt.Errorf("kmz: %s: %w", kmlFile.Name, err)
+	}
+	return route, nil
+}
+
+func parseKml(r io.Reader) (abstract.Route, error) {
+	decoder := xml.NewDecoder(r)
+
+	var lineName, documentName string
+	var line, points []abstract.Waypoint
+	var stack []string
+	placemarks := 0
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return abstract.Route{}, fmt.Errorf("malformed document: %w", err)
+		}
+
+		switch element := token.(type) {
+			case xml.StartElement:
+				if len(stack) == 0 && element.Name.Local != "kml" {
+					return abstract.Route{}, fmt.Errorf("malformed document: expected element type <kml> but have <%s>", element.Name.Local)
+				}
+				parent := ""
+				if len(stack) > 0 {
+					parent = stack[len(stack) - 1]
+				}
+
+				switch {
+					case element.Name.Local == "Placemark":
+						placemarks++
+						var placemark kmlPlacemark
+						if err := decoder.DecodeElement(&placemark, &element); err != nil {
+							return abstract.Route{}, fmt.Errorf("malformed document: %w", err)
+						}
+						lineStrings, pointGeometries := placemark.geometries()
+						for _, lineString := range lineStrings {
+							vertices, err := parseKmlCoordinates(lineString.Coordinates)
+							if err != nil {
+								return abstract.Route{}, fmt.Errorf("placemark %d: %w", placemarks, err)
+							}
+							if lineName == "" {
+								lineName = placemark.Name
+							}
+							line = append(line, vertices...)
+						}
+						for _, point := range pointGeometries {
+							vertices, err := parseKmlCoordinates(point.Coordinates)
+							if err != nil {
+								return abstract.Route{}, fmt.Errorf("placemark %d: %w", placemarks, err)
+							}
+							if len(vertices) != 1 {
+								return abstract.Route{}, fmt.Errorf("placemark %d: point must have one coordinate, got %d", placemarks, len(vertices))
+							}
+							vertices[0].WaypointName = strings.TrimSpace(placemark.Name)
+							points = append(points, vertices[0])
+						}
+
+					case element.Name.Local == "name" && parent == "Document" && documentName == "":
+						if err := decoder.DecodeElement(&documentName, &element); err != nil {
+							return abstract.Route{}, fmt.Errorf("malformed document: %w", err)
+						}
+
+					default:
+						stack = append(stack, element.Name.Local)
+				}
+
+			case xml.EndElement:
+				if len(stack) > 0 {
+					stack = stack[:len(stack) - 1]
+				}
+		}
+	}
+
+	route := abstract.Route{ RouteName: lineName, Waypoints: line }
+	if route.RouteName == "" {
+		route.RouteName = documentName
+	}
+	for _, point := range points {
+		if !nameVertex(route.Waypoints, point) {
+			route.Waypoints = append(route.Waypoints, point)
+		}
+	}
+
+	if err := validate(&route); err != nil {
+		return abstract.Route{}, err
+	}
+	return route, nil
+}
+
+func (placemark kmlPlacemark) geometries() ([]kmlGeometry, []kmlGeometry) {
+	var lineStrings, points []kmlGeometry
+	if placemark.LineString != nil {
+		lineStrings = append(lineStrings, *placemark.LineString)
+	}
+	if placemark.Point != nil {
+		points = append(points, *placemark.Point)
+	}
+	if placemark.MultiGeometry != nil {
+		lineStrings = append(lineStrings, placemark.MultiGeometry.LineStrings...)
+		points = append(points, placemark.MultiGeometry.Points...)
+	}
+	return lineStrings, points
+}
+
+// Gives the point name to the first unnamed vertex at the same position
+//
+func nameVertex(vertices []abstract.Waypoint, point abstract.Waypoint) bool {
+	for i, vertex := range vertices {
+		if vertex.WaypointName == "" &&
+			math.Abs(vertex.Lat - point.Lat) < sameCoordinateTolerance &&
+			math.Abs(vertex.Lon - point.Lon) < sameCoordinateTolerance {
+			vertices[i].WaypointName = point.WaypointName
+			return true
+		}
+	}
+	return false
+}
+
+// Parses KML coordinates: whitespace separated "lon,lat[,alt]" tuples
+//
+func parseKmlCoordinates(coordinates string) ([]abstract.Waypoint, error) {
+	var waypoints []abstract.Waypoint
+	for i, tuple := range strings.Fields(coordinates) {
+		values := strings.Split(tuple, ",")
+		if len(values) < 2 || len(values) > 3 {
+			return nil, fmt.Errorf("coordinate %d: %q is not lon,lat[,alt]", i + 1, tuple)
+		}
+		lat, lon, err := parseCoordinates(values[1], values[0])
+		if err != nil {
+			return nil, fmt.Errorf("coordinate %d: %w", i + 1, err)
+		}
+		waypoints = append(waypoints, abstract.Waypoint{ Lat: lat, Lon: lon })
+	}
+	if len(waypoints) == 0 {
+		return nil, errors.New("coordinates are empty")
+	}
+	return waypoints, nil
+}
+
+type kmlOutDocument struct {
+	XMLName  xml.Name        `xml:"kml"`
+	Xmlns    string          `xml:"xmlns,attr"`
+	Document kmlOutContainer `xml:"Document"`
+}
+
+type kmlOutContainer struct {
+	Name        string             `xml:"name,omitempty"`
+	Description string             `xml:"description,omitempty"`
+	Styles      []kmlOutStyle      `xml:"Style,omitempty"`
+	Placemarks  []kmlOutPlacemark  `xml:"Placemark,omitempty"`
+	Folders     []kmlOutContainer  `xml:"Folder,omitempty"`
+}
+
+type kmlOutStyle struct {
+	Id         string             `xml:"id,attr"`
+	IconStyle  *kmlOutIconStyle   `xml:"IconStyle,omitempty"`
+	LabelStyle *kmlOutLabelStyle  `xml:"LabelStyle,omitempty"`
+	LineStyle  *kmlOutLineStyle   `xml:"LineStyle,omitempty"`
+}
+
+type kmlOutIconStyle struct {
+	Color string  `xml:"color"`
+	Scale float64 `xml:"scale"`
+	Href  string  `xml:"Icon>href"`
+}
+
+type kmlOutLabelStyle struct {
+	Scale float64 `xml:"scale"`
+}
+
+type kmlOutLineStyle struct {
+	Color string `xml:"color"`
+	Width int    `xml:"width"`
+}
+
+type kmlOutPlacemark struct {
+	Name        string               `xml:"name,omitempty"`
+	Description string               `xml:"description,omitempty"`
+	StyleUrl    string               `xml:"styleUrl"`
+	Point       *kmlOutPoint         `xml:"Point,omitempty"`
+	LineString  *kmlOutLineString    `xml:"LineString,omitempty"`
+}
+
+type kmlOutPoint struct {
+	Coordinates string `xml:"coordinates"`
+}
+
+type kmlOutLineString struct {
+	Tessellate  int    `xml:"tessellate"`
+	Coordinates string `xml:"coordinates"`
+}
+
+// Shared styles of the exported document, colors are aabbggrr
+//
+var kmlStyles = []kmlOutStyle {
+	{
+		Id: "route",
+		LineStyle: &kmlOutLineStyle{ Color: "ff0080ff", Width: 3 },
+	},
+	{
+		Id: "waypoint",
+		IconStyle: &kmlOutIconStyle {
+			Color: "ff00d7ff",
+			Scale: 1.1,
+			Href: "http://maps.google.com/mapfiles/kml/shapes/placemark_circle.png",
+		},
+		LabelStyle: &kmlOutLabelStyle{ Scale: 0.9 },
+	},
+}
+
+// Writes routes as KML 2.2 document. Every route is a folder with the route line and
+// a placemark per waypoint, lines and waypoints share the styles of the document
+//
+func WriteKml(w io.Writer, name string, routes []abstract.Route) error {
+	doc := kmlOutDocument {
+		Xmlns: kmlNamespace,
+		Document: kmlOutContainer {
+			Name: name,
+			Description: "Created by " + generator,
+			Styles: kmlStyles,
+		},
+	}
+
+	for _, route := range routes {
+		folder := kmlOutContainer{ Name: route.RouteName }
+		if !route.UploadTime.IsZero() {
+			folder.Description = "Uploaded " + formatTime(route.UploadTime)
+		}
+
+		var line strings.Builder
+		for i, wp := range route.Waypoints {
+			coordinates := formatCoordinate(wp.Lon) + "," + formatCoordinate(wp.Lat) + ",0"
+			if i > 0 {
+				line.WriteString(" ")
+			}
+			line.WriteString(coordinates)
+			folder.Placemarks = append(folder.Placemarks, kmlOutPlacemark {
+				Name: wp.WaypointName,
+				Description: fmt.Sprintf("Waypoint %d", i + 1),
+				StyleUrl: "#waypoint",
+				Point: &kmlOutPoint{ Coordinates: coordinates },
+			})
+		}
+		if len(route.Waypoints) > 1 {
+			folder.Placemarks = append([]kmlOutPlacemark {{
+				Name: route.RouteName,
+				StyleUrl: "#route",
+				LineString: &kmlOutLineString{ Tessellate: 1, Coordinates: line.String() },
+			}}, folder.Placemarks...)
+		}
+		doc.Document.Folders = append(doc.Document.Folders, folder)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// Writes routes as KMZ archive with the KML document in doc.kml
+//
+func WriteKmz(w io.Writer, name string, routes []abstract.Route) error {
+	archive := zip.NewWriter(w)
+	file, err := archive.Create("doc.kml")
+	if err != nil {
+		return err
+	}
+	if err := WriteKml(file, name, routes); err != nil {
+		return err
+	}
+	return archive.Close()
+}
@@ -0,0 +1,205 @@
+package routeformat
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+
+	"IB.YasDataApi/abstract"
+)
+
+const kmlPath = `<?xml version="1.0" encoding="UTF-8"?>
+<kml xmlns="http://www.opengis.net/kml/2.2">
+  <Document>
+    <name>Gulf of Finland</name>
+    <Placemark>
+      <name>Tallinn - Helsinki</name>
+      <LineString>
+        <coordinates>
+          24.76528,59.44417,0 24.9,59.8,0
+          24.93545,60.16952,0
+        </coordinates>
+      </LineString>
+    </Placemark>
+    <Folder>
+      <Placemark><name>Tallinn</name><Point><coordinates>24.76528,59.44417,0</coordinates></Point></Placemark>
+      <Placemark><name>Helsinki</name><Point><coordinates>24.93545,60.16952</coordinates></Point></Placemark>
+      <Placemark><name>Porkkala</name><Point><coordinates>24.37,59.98,0</coordinates></Point></Placemark>
+    </Folder>
+  </Document>
+</kml>`
+
+func TestParseKml(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  abstract.Route
+	}{
+		{
+			name: "path with named marks",
+			input: kmlPath,
+			want: abstract.Route {
+				RouteName: "Tallinn - Helsinki",
+				Waypoints: []abstract.Waypoint {
+					{ WaypointName: "Tallinn", Lat: 59.44417, Lon: 24.76528 },
+					{ Lat: 59.8, Lon: 24.9 },
+					{ WaypointName: "Helsinki", Lat: 60.16952, Lon: 24.93545 },
+					{ WaypointName: "Porkkala", Lat: 59.98, Lon: 24.37 },
+				},
+			},
+		},
+		{
+			name: "points named by the document",
+			input: `<kml><Document><name>Marks</name>` +
+				`<Placemark><name>West</name><Point><coordinates>-179.5,-10</coordinates></Point></Placemark>` +
+				`<Placemark><name>East</name><Point><coordinates>179.5,10</coordinates></Point></Placemark>` +
+				`</Document></kml>`,
+			want: abstract.Route {
+				RouteName: "Marks",
+				Waypoints: []abstract.Waypoint {
+					{ WaypointName: "West", Lat: -10, Lon: -179.5 },
+					{ WaypointName: "East", Lat: 10, Lon: 179.5 },
+				},
+			},
+		},
+		{
+			name: "multi geometry",
+			input: `<kml><Placemark><name>Multi</name><MultiGeometry>` +
+				`<LineString><coordinates>1,2 3,4</coordinates></LineString>` +
+				`<Point><coordinates>3,4</coordinates></Point>` +
+				`</MultiGeometry></Placemark></kml>`,
+			want: abstract.Route {
+				RouteName: "Multi",
+				Waypoints: []abstract.Waypoint {
+					{ Lat: 2, Lon: 1 },
+					{ WaypointName: "Multi", Lat: 4, Lon: 3 },
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			route, err := ParseKml(strings.NewReader(test.input))
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			assertRoutesEqual(t, []abstract.Route{ test.want }, []abstract.Route{ route })
+		})
+	}
+}
+
+func TestParseKmlErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{ name: "empty", input: "", want: ErrNoWaypoints.Error() },
+		{ name: "truncated", input: kmlPath[:len(kmlPath) / 2], want: "kml: malformed document" },
+		{ name: "not kml", input: `<gpx><wpt lat="1" lon="2"/></gpx>`, want: "kml: malformed document: expected element type <kml> but have <gpx>" },
+		{ name: "no placemarks", input: `<kml><Document><name>Empty</name></Document></kml>`, want: ErrNoWaypoints.Error() },
+		{ name: "latitude first", input: `<kml><Placemark><Point><coordinates>24,91</coordinates></Point></Placemark></kml>`, want: "kml: placemark 1: coordinate 1: lat 91 is out of range" },
+		{ name: "missing latitude", input: `<kml><Placemark><LineString><coordinates>1,2 3</coordinates></LineString></Placemark></kml>`, want: `kml: placemark 1: coordinate 2: "3" is not lon,lat[,alt]` },
+		{ name: "empty coordinates", input: `<kml><Placemark><Point><coordinates> </coordinates></Point></Placemark></kml>`, want: "kml: placemark 1: coordinates are empty" },
+		{ name: "point with two coordinates", input: `<kml><Placemark><Point><coordinates>1,2 3,4</coordinates></Point></Placemark></kml>`, want: "kml: placemark 1: point must have one coordinate, got 2" },
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ParseKml(strings.NewReader(test.input))
+			if err == nil {
+				t.Fatalf("parse: no error, want %q", test.want)
+			}
+			if !strings.Contains(err.Error(), test.want) {
+				t.Errorf("error = %q, want %q", err, test.want)
+			}
+		})
+	}
+}
+
+func TestParseKmz(t *testing.T) {
+	data := zipArchive(t, map[string]string {
+		"files/other.kml": `<kml><Placemark><name>Other</name><Point><coordinates>1,1</coordinates></Point></Placemark></kml>`,
+		"doc.kml": kmlPath,
+		"files/icon.png": "\x89PNG",
+	})
+
+	route, err := ParseKmz(data)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if got, want := route.RouteName, "Tallinn - Helsinki"; got != want {
+		t.Errorf("route name = %q, want %q", got, want)
+	}
+	if got, want := len(route.Waypoints), 4; got != want {
+		t.Errorf("waypoints = %d, want %d", got, want)
+	}
+}
+
+func TestParseKmzErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{ name: "not an archive", data: []byte(kmlPath), want: "kmz: malformed archive" },
+		{ name: "truncated archive", data: zipArchive(t, map[string]string{ "doc.kml": kmlPath })[:40], want: "kmz: malformed archive" },
+		{ name: "no kml inside", data: zipArchive(t, map[string]string{ "doc.txt": kmlPath, "icon.png": "\x89PNG" }), want: "kmz: archive has no KML document" },
+		{ name: "empty archive", data: zipArchive(t, map[string]string{}), want: "kmz: archive has no KML document" },
+		{ name: "truncated kml inside", data: zipArchive(t, map[string]string{ "doc.kml": kmlPath[:len(kmlPath) / 2] }), want: "kmz: doc.kml: malformed document" },
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ParseKmz(test.data)
+			if err == nil {
+				t.Fatalf("parse: no error, want %q", test.want)
+			}
+			if !strings.Contains(err.Error(), test.want) {
+				t.Errorf("error = %q, want %q", err, test.want)
+			}
+		})
+	}
+}
+
+func TestWriteKmzRoundTrip(t *testing.T) {
+	routes := []abstract.Route{{
+		RouteName: "Monk & Trinity",
+		Waypoints: []abstract.Waypoint {
+			{ WaypointName: "Start", Lat: 59.43701, Lon: 24.75362 },
+			{ Lat: 59.5, Lon: 24.8 },
+			{ WaypointName: "<End>", Lat: -54.80191, Lon: -68.30295 },
+		},
+	}}
+
+	var output bytes.Buffer
+	if err := WriteKmz(&output, "Export", routes); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	route, err := ParseKmz(output.Bytes())
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	assertRoutesEqual(t, routes, []abstract.Route{ route })
+}
+
+func zipArchive(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var data bytes.Buffer
+	archive := zip.NewWriter(&data)
+	for name, content := range files {
+		file, err := archive.Create(name)
+		if err != nil {
+			t.Fatalf("zip: %v", err)
+		}
+		if _, err := file.Write([]byte(content)); err != nil {
+			t.Fatalf("zip: %v", err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatalf("zip: %v", err)
+	}
+	return data.Bytes()
+}
@@ -0,0 +1,180 @@
+package routeformat
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"IB.YasDataApi/abstract"
+)
+
+const (
+	DefaultTalkerId = "GP"
+	DefaultNmeaNameLength = 6
+)
+
+// NMEA 0183 sentence is at most 82 characters including $ and <CR><LF>,
+// the content between $ and * is at most 82 - 1 - 3 - 2 characters
+//
+const maxNmeaContentLength = 76
+
+type NmeaOptions struct {
+	TalkerId string
+	NameLength int
+}
+
+// Writes routes as NMEA 0183 sentences: $--WPL for every distinct waypoint followed by $--RTE
+// sentences of the route. Route is identified by its id. Waypoint names are truncated to the
+// name length, see nmeaNames
+//
+func WriteNmea(w io.Writer, routes []abstract.Route, options NmeaOptions) error {
+	if options.TalkerId == "" {
+		options.TalkerId = DefaultTalkerId
+	}
+	if options.NameLength <= 0 {
+		options.NameLength = DefaultNmeaNameLength
+	}
+
+	names := nmeaNames(routes, options.NameLength)
+	written := map[string]bool{}
+	for r, route := range routes {
+		for i, wp := range route.Waypoints {
+			name := names[r][i]
+			if written[name] {
+				continue
+			}
+			written[name] = true
+			lat, latHemisphere := nmeaDegrees(wp.Lat, 2, "N", "S")
+			lon, lonHemisphere := nmeaDegrees(wp.Lon, 3, "E", "W")
+			sentence := fmt.Sprintf("%sWPL,%s,%s,%s,%s,%s", options.TalkerId, lat, latHemisphere, lon, lonHemisphere, name)
+			if _, err := io.WriteString(w, nmeaSentence(sentence)); err != nil {
+				return err
+			}
+		}
+	}
+
+	for r, route := range routes {
+		if len(route.Waypoints) == 0 {
+			continue
+		}
+		for _, sentence := range nmeaRte(options.TalkerId, strconv.Itoa(int(route.RouteId)), names[r]) {
+			if _, err := io.WriteString(w, nmeaSentence(sentence)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Returns NMEA names of the waypoints of every route. Names are upper case letters, digits, - and _
+// truncated to the length, unnamed waypoints are WP<order>. Waypoints with the same name and position
+// share the name. Names colliding after truncation get a numeric suffix in place of the last
+// characters in the order of waypoints, so the same route always gets the same names
+//
+func nmeaNames(routes []abstract.Route, length int) [][]string {
+	type position struct {
+		name string
+		lat, lon float64
+	}
+	owners := map[string]position{}
+	result := make([][]string, len(routes))
+	for r, route := range routes {
+		result[r] = make([]string, len(route.Waypoints))
+		for i, wp := range route.Waypoints {
+			base := nmeaSanitize(wp.WaypointName)
+			if base == "" {
+				base = fmt.Sprintf("WP%d", i + 1)
+			}
+			owner := position{ name: base, lat: roundNmea(wp.Lat), lon: roundNmea(wp.Lon) }
+
+			name := truncate(base, length)
+			for suffix := 1; ; suffix++ {
+				existing, used := owners[name]
+				if !used || existing == owner {
+					break
+				}
+				tail := strconv.Itoa(suffix)
+				name = truncate(base, length - len(tail)) + tail
+			}
+			owners[name] = owner
+			result[r][i] = name
+		}
+	}
+	return result
+}
+
+// Splits waypoint names into $--RTE sentences which fit the sentence length
+//
+func nmeaRte(talkerId string, routeId string, names []string) []string {
+	for digits := 1; ; digits++ {
+		var chunks [][]string
+		var chunk []string
+		headerLength := len(talkerId) + len("RTE,,,c,") + 2 * digits + len(routeId)
+		length := headerLength
+		for _, name := range names {
+			if len(chunk) > 0 && length + 1 + len(name) > maxNmeaContentLength {
+				chunks = append(chunks, chunk)
+				chunk, length = nil, headerLength
+			}
+			chunk = append(chunk, name)
+			length += 1 + len(name)
+		}
+		chunks = append(chunks, chunk)
+
+		if len(strconv.Itoa(len(chunks))) > digits {
+			continue
+		}
+		sentences := make([]string, 0, len(chunks))
+		for i, chunk := range chunks {
+			sentences = append(sentences, fmt.Sprintf("%sRTE,%d,%d,c,%s,%s", talkerId, len(chunks), i + 1, routeId, strings.Join(chunk, ",")))
+		}
+		return sentences
+	}
+}
+
+// Returns $<content>*<checksum><CR><LF>, checksum is XOR of the content bytes
+//
+func nmeaSentence(content string) string {
+	var checksum byte
+	for i := 0; i < len(content); i++ {
+		checksum ^= content[i]
+	}
+	return fmt.Sprintf("$%s*%02X\r\n", content, checksum)
+}
+
+// Formats degrees as (d)ddmm.mmmm and the hemisphere
+//
+func nmeaDegrees(value float64, degreeDigits int, positive string, negative string) (string, string) {
+	hemisphere := positive
+	if value < 0 {
+		hemisphere = negative
+	}
+	minutes := int64(math.Round(math.Abs(value) * 60 * 10000))
+	return fmt.Sprintf("%0*d%07.4f", degreeDigits, minutes / 600000, float64(minutes % 600000) / 10000), hemisphere
+}
+
+func roundNmea(value float64) float64 {
+	return math.Round(value * 60 * 10000)
+}
+
+func nmeaSanitize(name string) string {
+	var builder strings.Builder
+	for _, c := range strings.ToUpper(name) {
+		if (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '-' || c == '_' {
+			builder.WriteRune(c)
+		}
+	}
+	return builder.String()
+}
+
+func truncate(value string, length int) string {
+	if length < 0 {
+		length = 0
+	}
+	if len(value) > length {
+		return value[:length]
+	}
+	return value
+}
@@ -0,0 +1,218 @@
+package routeformat
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"IB.YasDataApi/abstract"
+)
+
+func TestNmeaSentence(t *testing.T) {
+	// Reference sentences of the NMEA 0183 documentation
+	//
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{ content: "GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,", want: "$GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,*47\r\n" },
+		{ content: "GPWPL,4917.16,N,12310.64,W,003", want: "$GPWPL,4917.16,N,12310.64,W,003*65\r\n" },
+		{ content: "GPRTE,2,1,c,0,PBRCPK,PBRTO,PTELGR,PPLAND,PYAMBU,PPFAIR,PWARRN,PMORTL,PLISMR", want: "$GPRTE,2,1,c,0,PBRCPK,PBRTO,PTELGR,PPLAND,PYAMBU,PPFAIR,PWARRN,PMORTL,PLISMR*73\r\n" },
+		{ content: "", want: "$*00\r\n" },
+	}
+
+	for _, test := range tests {
+		t.Run(test.content, func(t *testing.T) {
+			if got := nmeaSentence(test.content); got != test.want {
+				t.Errorf("sentence = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestNmeaDegrees(t *testing.T) {
+	tests := []struct {
+		value      float64
+		digits     int
+		want       string
+		hemisphere string
+	}{
+		{ value: 49.286, digits: 2, want: "4917.1600", hemisphere: "N" },
+		{ value: -123.1773333, digits: 3, want: "12310.6400", hemisphere: "W" },
+		{ value: 0, digits: 2, want: "0000.0000", hemisphere: "N" },
+		{ value: -0.5, digits: 2, want: "0030.0000", hemisphere: "S" },
+		{ value: 180, digits: 3, want: "18000.0000", hemisphere: "E" },
+		{ value: -90, digits: 2, want: "9000.0000", hemisphere: "S" },
+		// 59°59.99999' rounds to the next degree, not to 59°60.0000'
+		{ value: 59.99999999, digits: 2, want: "6000.0000", hemisphere: "N" },
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprint(test.value), func(t *testing.T) {
+			got, hemisphere := nmeaDegrees(test.value, test.digits, "N", "S")
+			if test.digits == 3 {
+				got, hemisphere = nmeaDegrees(test.value, test.digits, "E", "W")
+			}
+			if got != test.want || hemisphere != test.hemisphere {
+				t.Errorf("degrees = %q %q, want %q %q", got, hemisphere, test.want, test.hemisphere)
+			}
+		})
+	}
+}
+
+func TestWriteNmea(t *testing.T) {
+	routes := []abstract.Route{{
+		RouteId: 957,
+		Waypoints: []abstract.Waypoint {
+			{ WaypointName: "Tallinn", Lat: 59.44417, Lon: 24.76528 },
+			{ Lat: -33.85679, Lon: 151.2153 },
+			{ WaypointName: "Tallinn", Lat: 59.44417, Lon: 24.76528 },
+		},
+	}}
+
+	var output strings.Builder
+	if err := WriteNmea(&output, routes, NmeaOptions{}); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	want := nmeaSentence("GPWPL,5926.6502,N,02445.9168,E,TALLIN") +
+		nmeaSentence("GPWPL,3351.4074,S,15112.9180,E,WP2") +
+		nmeaSentence("GPRTE,1,1,c,957,TALLIN,WP2,TALLIN")
+	if got := output.String(); got != want {
+		t.Errorf("nmea = %q, want %q", got, want)
+	}
+
+	output.Reset()
+	if err := WriteNmea(&output, routes, NmeaOptions{ TalkerId: "II", NameLength: 10 }); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if got, want := output.String(), "$IIRTE,1,1,c,957,TALLINN,WP2,TALLINN*"; !strings.Contains(got, want) {
+		t.Errorf("nmea = %q, want it to contain %q", got, want)
+	}
+}
+
+func TestNmeaNames(t *testing.T) {
+	tests := []struct {
+		name   string
+		routes []abstract.Route
+		length int
+		want   [][]string
+	}{
+		{
+			name: "sanitized and unnamed",
+			routes: []abstract.Route{{ Waypoints: []abstract.Waypoint {
+				{ WaypointName: "Põhja kaart", Lat: 1, Lon: 1 },
+				{ WaypointName: "", Lat: 2, Lon: 2 },
+				{ WaypointName: "!!!", Lat: 3, Lon: 3 },
+				{ WaypointName: "no_2-b", Lat: 4, Lon: 4 },
+			}}},
+			length: 6,
+			want: [][]string{{ "PHJAKA", "WP2", "WP3", "NO_2-B" }},
+		},
+		{
+			name: "same name and position share the name across routes",
+			routes: []abstract.Route {
+				{ Waypoints: []abstract.Waypoint{{ WaypointName: "Mark", Lat: 1, Lon: 1 }} },
+				{ Waypoints: []abstract.Waypoint{{ WaypointName: "MARK", Lat: 1, Lon: 1 }} },
+			},
+			length: 6,
+			want: [][]string{{ "MARK" }, { "MARK" }},
+		},
+		{
+			name: "same name at another position",
+			routes: []abstract.Route{{ Waypoints: []abstract.Waypoint {
+				{ WaypointName: "Mark", Lat: 1, Lon: 1 },
+				{ WaypointName: "Mark", Lat: 1, Lon: 1.001 },
+				{ WaypointName: "Mark", Lat: 1, Lon: 1.002 },
+			}}},
+			length: 6,
+			want: [][]string{{ "MARK", "MARK1", "MARK2" }},
+		},
+		{
+			name: "collision after truncation",
+			routes: []abstract.Route{{ Waypoints: []abstract.Waypoint {
+				{ WaypointName: "Harbour East", Lat: 1, Lon: 1 },
+				{ WaypointName: "Harbour West", Lat: 2, Lon: 2 },
+				{ WaypointName: "Harbourside", Lat: 3, Lon: 3 },
+				{ WaypointName: "Harbour West", Lat: 2, Lon: 2 },
+			}}},
+			length: 6,
+			want: [][]string{{ "HARBOU", "HARBO1", "HARBO2", "HARBO1" }},
+		},
+		{
+			name: "suffix takes more characters",
+			routes: []abstract.Route{{ Waypoints: manyWaypoints("Buoy", 12) }},
+			length: 4,
+			want: [][]string{{ "BUOY", "BUO1", "BUO2", "BUO3", "BUO4", "BUO5", "BUO6", "BUO7", "BUO8", "BUO9", "BU10", "BU11" }},
+		},
+		{
+			name: "unnamed waypoint collides with the name",
+			routes: []abstract.Route{{ Waypoints: []abstract.Waypoint {
+				{ WaypointName: "WP2", Lat: 1, Lon: 1 },
+				{ Lat: 2, Lon: 2 },
+			}}},
+			length: 6,
+			want: [][]string{{ "WP2", "WP21" }},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := nmeaNames(test.routes, test.length)
+			if fmt.Sprint(got) != fmt.Sprint(test.want) {
+				t.Errorf("names = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestNmeaRteSplitsSentences(t *testing.T) {
+	// "GPRTE,1,1,c,1," and NAME,NAME1..NAME9 take 73 characters, NAME10 does not fit
+	//
+	tests := []struct {
+		name      string
+		routeId   string
+		count     int
+		sentences int
+	}{
+		{ name: "empty route", routeId: "1", count: 0, sentences: 1 },
+		{ name: "one sentence", routeId: "1", count: 10, sentences: 1 },
+		{ name: "two sentences", routeId: "1", count: 11, sentences: 2 },
+		{ name: "two digit sentence count", routeId: "2147483647", count: 100, sentences: 15 },
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			names := nmeaNames([]abstract.Route{{ Waypoints: manyWaypoints("Name", test.count) }}, 6)[0]
+			sentences := nmeaRte(DefaultTalkerId, test.routeId, names)
+			if len(sentences) != test.sentences {
+				t.Fatalf("sentences = %d, want %d", len(sentences), test.sentences)
+			}
+
+			var joined []string
+			for i, sentence := range sentences {
+				if len(sentence) > maxNmeaContentLength {
+					t.Errorf("sentence %d is %d characters: %q", i + 1, len(sentence), sentence)
+				}
+				if line := nmeaSentence(sentence); len(line) > 82 {
+					t.Errorf("line %d is %d characters: %q", i + 1, len(line), line)
+				}
+				fields := strings.Split(sentence, ",")
+				if want := fmt.Sprintf("GPRTE,%d,%d,c,%s", len(sentences), i + 1, test.routeId); strings.Join(fields[:5], ",") != want {
+					t.Errorf("sentence %d header = %q, want %q", i + 1, strings.Join(fields[:5], ","), want)
+				}
+				joined = append(joined, fields[5:]...)
+			}
+			if len(names) > 0 && strings.Join(joined, ",") != strings.Join(names, ",") {
+				t.Errorf("names = %v, want %v", joined, names)
+			}
+		})
+	}
+}
+
+func manyWaypoints(name string, count int) []abstract.Waypoint {
+	waypoints := make([]abstract.Waypoint, 0, count)
+	for i := 0; i < count; i++ {
+		waypoints = append(waypoints, abstract.Waypoint{ WaypointName: name, Lat: float64(i) / 100, Lon: 1 })
+	}
+	return waypoints
+}
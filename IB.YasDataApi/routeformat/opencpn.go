@@ -0,0 +1,66 @@
+package routeformat
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"IB.YasDataApi/abstract"
+)
+
+// GPX written by OpenCPN. Every route is a separate <rte> with the start and the end
+// in the opencpn extensions, <wpt> elements are the marks which do not belong to the routes
+//
+type openCpnDocument struct {
+	XMLName   xml.Name       `xml:"gpx"`
+	Waypoints []gpxPoint     `xml:"wpt"`
+	Routes    []openCpnRoute `xml:"rte"`
+}
+
+type openCpnRoute struct {
+	Name   string     `xml:"name"`
+	Start  string     `xml:"extensions>start"`
+	End    string     `xml:"extensions>end"`
+	Points []gpxPoint `xml:"rtept"`
+}
+
+// Parses OpenCPN GPX into the route. Only the first route with points is taken, so the marks
+// exported along with the route are not mixed into it. The route is named by its name or by
+// its start and end. A file without routes is imported as the list of marks
+//
+func ParseOpenCpn(r io.Reader) (abstract.Route, error) {
+	var doc openCpnDocument
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return abstract.Route{}, fmt.Errorf("opencpn: malformed document: %w", err)
+	}
+
+	var route abstract.Route
+	points := doc.Waypoints
+	pointElement := "wpt"
+	for _, rte := range doc.Routes {
+		if len(rte.Points) == 0 {
+			continue
+		}
+		route.RouteName = strings.TrimSpace(rte.Name)
+		if route.RouteName == "" && rte.Start != "" && rte.End != "" {
+			route.RouteName = strings.TrimSpace(rte.Start) + " - " + strings.TrimSpace(rte.End)
+		}
+		points = rte.Points
+		pointElement = "rtept"
+		break
+	}
+
+	for i, point := range points {
+		waypoint, err := point.waypoint()
+		if err != nil {
+			return abstract.Route{}, fmt.Errorf("opencpn: %s %d: %w", pointElement, i + 1, err)
+		}
+		route.Waypoints = append(route.Waypoints, waypoint)
+	}
+
+	if err := validate(&route); err != nil {
+		return abstract.Route{}, fmt.Errorf("opencpn: %w", err)
+	}
+	return route, nil
+}
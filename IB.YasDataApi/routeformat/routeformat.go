@@ -0,0 +1,193 @@
+package routeformat
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"IB.YasDataApi/abstract"
+)
+
+// Upper limit of waypoints in the imported route, keeps the command within the kafka message size
+//
+const MaxWaypoints = 5000
+
+// Application name written into the exported files
+//
+const generator = "YA-Sailing"
+
+// Route file formats
+//
+const (
+	FormatGpx = "gpx"
+	FormatOpenCpn = "opencpn"
+	FormatKml = "kml"
+	FormatKmz = "kmz"
+	FormatExpedition = "expedition"
+)
+
+var ErrNoWaypoints = errors.New("no route or way points were found")
+
+var ErrUnknownFormat = errors.New("unknown route file format")
+
+var utf8Bom = []byte("\xef\xbb\xbf")
+
+// Detects format of the route file by its content: zip archive is KMZ, XML is GPX, OpenCPN GPX or KML
+// by the root element and the creator, comma separated text is Expedition CSV
+//
+func DetectFormat(data []byte) (string, error) {
+	data = bytes.TrimPrefix(data, utf8Bom)
+	if bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		return FormatKmz, nil
+	}
+
+	if bytes.HasPrefix(bytes.TrimLeft(data, " \t\r\n"), []byte("<")) {
+		root, err := xmlRoot(data)
+		if err != nil {
+			return "", fmt.Errorf("%w: %s", ErrUnknownFormat, err)
+		}
+		switch root.Name.Local {
+			case "gpx":
+				if isOpenCpn(root) {
+					return FormatOpenCpn, nil
+				}
+				return FormatGpx, nil
+			case "kml":
+				return FormatKml, nil
+		}
+		return "", fmt.Errorf("%w: <%s> document", ErrUnknownFormat, root.Name.Local)
+	}
+
+	if isCsv(data) {
+		return FormatExpedition, nil
+	}
+	return "", ErrUnknownFormat
+}
+
+// Parses route file of the format
+//
+func Parse(format string, data []byte) (abstract.Route, error) {
+	switch format {
+		case FormatGpx:
+			return ParseGpx(bytes.NewReader(bytes.TrimPrefix(data, utf8Bom)))
+		case FormatOpenCpn:
+			return ParseOpenCpn(bytes.NewReader(bytes.TrimPrefix(data, utf8Bom)))
+		case FormatKml:
+			return ParseKml(bytes.NewReader(bytes.TrimPrefix(data, utf8Bom)))
+		case FormatKmz:
+			return ParseKmz(data)
+		case FormatExpedition:
+			return ParseExpedition(bytes.NewReader(bytes.TrimPrefix(data, utf8Bom)))
+	}
+	return abstract.Route{}, fmt.Errorf("%w: %s", ErrUnknownFormat, format)
+}
+
+func xmlRoot(data []byte) (xml.StartElement, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return xml.StartElement{}, err
+		}
+		if element, ok := token.(xml.StartElement); ok {
+			return element, nil
+		}
+	}
+}
+
+// OpenCPN writes its name into the creator attribute and declares opencpn namespace for the extensions
+//
+func isOpenCpn(root xml.StartElement) bool {
+	for _, attr := range root.Attr {
+		if attr.Name.Local == "creator" && strings.Contains(strings.ToLower(attr.Value), "opencpn") {
+			return true
+		}
+		if attr.Name.Space == "xmlns" && attr.Name.Local == "opencpn" {
+			return true
+		}
+	}
+	return false
+}
+
+// Text is CSV if the first line which is not a comment has a comma and there are no binary bytes
+//
+func isCsv(data []byte) bool {
+	if !utf8.Valid(data) || bytes.IndexByte(data, 0) >= 0 {
+		return false
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || isCsvComment(line) {
+			continue
+		}
+		return strings.Contains(line, ",")
+	}
+	return false
+}
+
+// Parses latitude and longitude attributes of the point
+//
+func parseCoordinates(lat string, lon string) (float64, float64, error) {
+	latValue, err := parseCoordinate("lat", lat, 90)
+	if err != nil {
+		return 0, 0, err
+	}
+	lonValue, err := parseCoordinate("lon", lon, 180)
+	if err != nil {
+		return 0, 0, err
+	}
+	return latValue, lonValue, nil
+}
+
+func parseCoordinate(name string, value string, limit float64) (float64, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, fmt.Errorf("%s is missing", name)
+	}
+	coordinate, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(coordinate) || math.IsInf(coordinate, 0) {
+		return 0, fmt.Errorf("%s %q is not a number", name, value)
+	}
+	return checkCoordinate(name, coordinate, limit)
+}
+
+func checkCoordinate(name string, coordinate float64, limit float64) (float64, error) {
+	if coordinate < -limit || coordinate > limit {
+		return 0, fmt.Errorf("%s %v is out of range [-%v, %v]", name, coordinate, limit, limit)
+	}
+	return coordinate, nil
+}
+
+// Checks the imported route and numbers its waypoints in the order of the file
+//
+func validate(route *abstract.Route) error {
+	if len(route.Waypoints) == 0 {
+		return ErrNoWaypoints
+	}
+	if len(route.Waypoints) > MaxWaypoints {
+		return fmt.Errorf("too many way points: %d, the limit is %d", len(route.Waypoints), MaxWaypoints)
+	}
+	for i := range route.Waypoints {
+		route.Waypoints[i].OrderId = int32(i)
+	}
+	route.RouteName = strings.TrimSpace(route.RouteName)
+	return nil
+}
+
+// Formats coordinate with the shortest representation which parses back to the same value
+//
+func formatCoordinate(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// Formats time as UTC xsd:dateTime
+//
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
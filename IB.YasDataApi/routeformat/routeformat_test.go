@@ -0,0 +1,157 @@
+package routeformat
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"IB.YasDataApi/abstract"
+)
+
+const openCpnGpx = `<?xml version="1.0"?>
+<gpx version="1.1" creator="OpenCPN" xmlns="http://www.topografix.com/GPX/1/1" xmlns:opencpn="http://www.opencpn.org">
+  <wpt lat="10" lon="10"><name>Mark</name></wpt>
+  <rte>
+    <extensions><opencpn:start>Tallinn</opencpn:start><opencpn:end>Helsinki</opencpn:end></extensions>
+  </rte>
+  <rte>
+    <extensions><opencpn:start>Tallinn</opencpn:start><opencpn:end>Helsinki</opencpn:end></extensions>
+    <rtept lat="59.44417" lon="24.76528"><name>001</name></rtept>
+    <rtept lat="60.16952" lon="24.93545"><name>002</name></rtept>
+  </rte>
+  <rte><name>Second</name><rtept lat="1" lon="1"/></rte>
+</gpx>`
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{ name: "gpx", input: `<?xml version="1.0"?><gpx creator="Navionics"><rte/></gpx>`, want: FormatGpx },
+		{ name: "gpx with bom and comment", input: "\xef\xbb\xbf\r\n<!-- export --><gpx></gpx>", want: FormatGpx },
+		{ name: "opencpn creator", input: `<gpx creator="OpenCPN"></gpx>`, want: FormatOpenCpn },
+		{ name: "opencpn namespace", input: `<gpx creator="other" xmlns:opencpn="http://www.opencpn.org"></gpx>`, want: FormatOpenCpn },
+		{ name: "kml", input: kmlPath, want: FormatKml },
+		{ name: "kmz", input: string(zipArchive(t, map[string]string{ "doc.kml": kmlPath })), want: FormatKmz },
+		{ name: "kmz without kml", input: string(zipArchive(t, map[string]string{ "doc.txt": "" })), want: FormatKmz },
+		{ name: "expedition", input: "! marks\nName,Lat,Lon\nA,1,2\n", want: FormatExpedition },
+		{ name: "truncated gpx", input: `<gpx><rte><rtept lat="1" lon="2">`, want: FormatGpx },
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			format, err := DetectFormat([]byte(test.input))
+			if err != nil {
+				t.Fatalf("detect: %v", err)
+			}
+			if format != test.want {
+				t.Errorf("format = %q, want %q", format, test.want)
+			}
+		})
+	}
+}
+
+func TestDetectFormatErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{ name: "empty", input: "" },
+		{ name: "blank", input: " \r\n\t" },
+		{ name: "only comments", input: "! marks\n# more\n" },
+		{ name: "text without commas", input: "Tallinn 59.4 24.7\n" },
+		{ name: "binary", input: "A,1,2\x00\x01" },
+		{ name: "invalid utf-8", input: "A,1,\xff\n" },
+		{ name: "other xml", input: `<svg xmlns="http://www.w3.org/2000/svg"></svg>` },
+		{ name: "broken xml", input: "<" },
+		{ name: "unclosed comment", input: "<!-- export" },
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			format, err := DetectFormat([]byte(test.input))
+			if !errors.Is(err, ErrUnknownFormat) {
+				t.Errorf("detect = %q, %v, want %v", format, err, ErrUnknownFormat)
+			}
+		})
+	}
+}
+
+func TestParseDetected(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  abstract.Route
+	}{
+		{
+			name: "opencpn first route with points",
+			input: openCpnGpx,
+			want: abstract.Route {
+				RouteName: "Tallinn - Helsinki",
+				Waypoints: []abstract.Waypoint {
+					{ WaypointName: "001", Lat: 59.44417, Lon: 24.76528 },
+					{ WaypointName: "002", Lat: 60.16952, Lon: 24.93545 },
+				},
+			},
+		},
+		{
+			name: "opencpn marks",
+			input: `<gpx creator="OpenCPN"><wpt lat="10" lon="-10"><name>Mark</name></wpt></gpx>`,
+			want: abstract.Route{ Waypoints: []abstract.Waypoint{{ WaypointName: "Mark", Lat: 10, Lon: -10 }} },
+		},
+		{
+			name: "expedition with bom",
+			input: "\xef\xbb\xbfName,Lat,Lon\nA,1,2\n",
+			want: abstract.Route{ Waypoints: []abstract.Waypoint{{ WaypointName: "A", Lat: 1, Lon: 2 }} },
+		},
+		{
+			name: "kmz",
+			input: string(zipArchive(t, map[string]string {
+				"doc.kml": `<kml><Placemark><name>A</name><Point><coordinates>2,1</coordinates></Point></Placemark></kml>`,
+			})),
+			want: abstract.Route{ Waypoints: []abstract.Waypoint{{ WaypointName: "A", Lat: 1, Lon: 2 }} },
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			format, err := DetectFormat([]byte(test.input))
+			if err != nil {
+				t.Fatalf("detect: %v", err)
+			}
+			route, err := Parse(format, []byte(test.input))
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			assertRoutesEqual(t, []abstract.Route{ test.want }, []abstract.Route{ route })
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		input  string
+		want   string
+	}{
+		{ name: "unknown format", format: "nmea", input: "$GPRTE", want: ErrUnknownFormat.Error() },
+		{ name: "truncated opencpn", format: FormatOpenCpn, input: openCpnGpx[:len(openCpnGpx) / 2], want: "opencpn: malformed document" },
+		{ name: "opencpn bad point", format: FormatOpenCpn, input: `<gpx><rte><rtept lat="1"/></rte></gpx>`, want: "opencpn: rtept 1: lon is missing" },
+		{ name: "opencpn empty", format: FormatOpenCpn, input: `<gpx creator="OpenCPN"></gpx>`, want: ErrNoWaypoints.Error() },
+		{ name: "kmz without kml", format: FormatKmz, input: string(zipArchive(t, map[string]string{ "doc.txt": "" })), want: "kmz: archive has no KML document" },
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Parse(test.format, []byte(test.input))
+			if err == nil {
+				t.Fatalf("parse: no error, want %q", test.want)
+			}
+			if !strings.Contains(err.Error(), test.want) {
+				t.Errorf("error = %q, want %q", err, test.want)
+			}
+		})
+	}
+}
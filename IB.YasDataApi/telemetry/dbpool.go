@@ -0,0 +1,68 @@
+package telemetry
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"go.opentelemetry.io/otel/metric/instrument"
+	"go.opentelemetry.io/otel/metric/unit"
+)
+
+// Exposes statistics of the postgres connection pool as metrics.
+// Values are read from the pool on every metrics collection.
+// Does nothing if the telemetry has not been set up
+//
+func (telemetry *Telemetry) RegisterDbPoolMetrics(pool *pgxpool.Pool) error {
+
+	if telemetry.Meter == nil {
+		return nil
+	}
+
+	instruments := telemetry.Meter.AsyncInt64()
+
+	totalConns, err := instruments.Gauge("wc_dbpool_total_conns")
+	if err != nil {
+		return err
+	}
+	idleConns, err := instruments.Gauge("wc_dbpool_idle_conns")
+	if err != nil {
+		return err
+	}
+	acquiredConns, err := instruments.Gauge("wc_dbpool_acquired_conns")
+	if err != nil {
+		return err
+	}
+	maxConns, err := instruments.Gauge("wc_dbpool_max_conns")
+	if err != nil {
+		return err
+	}
+	acquireCount, err := instruments.Counter("wc_dbpool_acquire_count")
+	if err != nil {
+		return err
+	}
+	emptyAcquireCount, err := instruments.Counter("wc_dbpool_empty_acquire_count")
+	if err != nil {
+		return err
+	}
+	acquireDuration, err := instruments.Counter(
+		"wc_dbpool_acquire_duration", instrument.WithUnit(unit.Milliseconds))
+	if err != nil {
+		return err
+	}
+
+	return telemetry.Meter.RegisterCallback(
+		[]instrument.Asynchronous{
+			totalConns, idleConns, acquiredConns, maxConns,
+			acquireCount, emptyAcquireCount, acquireDuration,
+		},
+		func(ctx context.Context) {
+			stat := pool.Stat()
+			totalConns.Observe(ctx, int64(stat.TotalConns()))
+			idleConns.Observe(ctx, int64(stat.IdleConns()))
+			acquiredConns.Observe(ctx, int64(stat.AcquiredConns()))
+			maxConns.Observe(ctx, int64(stat.MaxConns()))
+			acquireCount.Observe(ctx, stat.AcquireCount())
+			emptyAcquireCount.Observe(ctx, stat.EmptyAcquireCount())
+			acquireDuration.Observe(ctx, stat.AcquireDuration().Milliseconds())
+		})
+}
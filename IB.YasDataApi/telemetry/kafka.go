@@ -0,0 +1,57 @@
+package telemetry
+
+import (
+	"context"
+
+	"github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// W3C trace context and baggage propagator used for http requests and kafka messages
+//
+var Propagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+
+// Adapts kafka message headers to propagation.TextMapCarrier
+//
+type MessageCarrier struct {
+	message *kafka.Message
+}
+
+func (carrier MessageCarrier) Get(key string) string {
+	for _, h := range carrier.message.Headers {
+		if h.Key == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+func (carrier MessageCarrier) Set(key string, value string) {
+	for i, h := range carrier.message.Headers {
+		if h.Key == key {
+			carrier.message.Headers[i].Value = []byte(value)
+			return
+		}
+	}
+	carrier.message.Headers = append(carrier.message.Headers, kafka.Header{ Key: key, Value: []byte(value) })
+}
+
+func (carrier MessageCarrier) Keys() []string {
+	keys := make([]string, 0, len(carrier.message.Headers))
+	for _, h := range carrier.message.Headers {
+		keys = append(keys, h.Key)
+	}
+	return keys
+}
+
+// Writes trace context of ctx into the message headers (traceparent, tracestate, baggage)
+//
+func InjectMessage(ctx context.Context, message *kafka.Message) {
+	Propagator.Inject(ctx, MessageCarrier{ message: message })
+}
+
+// Returns context with the trace context taken from the message headers
+//
+func ExtractMessage(ctx context.Context, message kafka.Message) context.Context {
+	return Propagator.Extract(ctx, MessageCarrier{ message: &message })
+}
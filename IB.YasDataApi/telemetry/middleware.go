@@ -13,8 +13,16 @@ import (
 )
 
 
+// Traces and meters REST requests. Requests pass through untouched if the telemetry has not been set up
+//
 func Middleware(telemetry Telemetry) gin.HandlerFunc {
 
+	if telemetry.Meter == nil || telemetry.TraceProvider == nil {
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
+
 	requestDuration, err := telemetry.Meter.SyncInt64().Histogram(
 		"wc_restapi_request_duration",
 		instrument.WithUnit(unit.Milliseconds))
@@ -28,34 +36,36 @@ func Middleware(telemetry Telemetry) gin.HandlerFunc {
 		
 		// Trace request
 		//
-		propgator := propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
-		parentCtx := propgator.Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
-		_, span := tracer.Start(parentCtx, "/restapi",  trace.WithAttributes(
+		parentCtx := Propagator.Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+		ctx, span := tracer.Start(parentCtx, "/restapi",  trace.WithAttributes(
 			attribute.KeyValue {
 				Key: "path",
 				Value: attribute.StringValue(c.Request.URL.Path),
 			},
 		))	
 		defer span.End()
+		c.Request = c.Request.WithContext(ctx)
 
 		// Meter request
 		//
 
 		startTime := time.Now()
-		defer requestDuration.Record(c.Request.Context(), time.Since(startTime).Milliseconds(), 
-			attribute.KeyValue {
-				Key: "status",
-				Value: attribute.IntValue(c.Writer.Status()),
-			},
-			attribute.KeyValue {
-				Key: "path",
-				Value: attribute.StringValue(c.Request.URL.Path),
-			},
-			attribute.KeyValue {
-				Key: "query",
-				Value: attribute.StringValue(c.Request.URL.RawQuery),
-			},
-		)
+		defer func() {
+			requestDuration.Record(c.Request.Context(), time.Since(startTime).Milliseconds(), 
+				attribute.KeyValue {
+					Key: "status",
+					Value: attribute.IntValue(c.Writer.Status()),
+				},
+				attribute.KeyValue {
+					Key: "path",
+					Value: attribute.StringValue(c.Request.URL.Path),
+				},
+				attribute.KeyValue {
+					Key: "query",
+					Value: attribute.StringValue(c.Request.URL.RawQuery),
+				},
+			)
+		}()
 
 		c.Next()
 	}
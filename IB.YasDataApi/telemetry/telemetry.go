@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
 	"go.opentelemetry.io/otel/metric"
@@ -25,7 +26,7 @@ type Telemetry struct {
 
 	// Instance of the metric provider
 	//
-	MeterProvider sdkmetric.MeterProvider
+	MeterProvider *sdkmetric.MeterProvider
 
 	// actual meter
 	//
@@ -33,7 +34,7 @@ type Telemetry struct {
 
 	// Instance of the metric provider
 	//
-	TraceProvider trace.TracerProvider
+	TraceProvider *trace.TracerProvider
 
 	// context
 	//
@@ -42,10 +43,12 @@ type Telemetry struct {
 	uptimeGauge asyncint64.Gauge
 }
 
-func Setup(config abstract.Config) (Telemetry, error) {
+// Setups metric and trace exporters for the service and registers them as global providers
+//
+func Setup(config abstract.Config, serviceName string) (Telemetry, error) {
 
 	ctx := context.Background()
-	res, err := resource.New(ctx, resource.WithAttributes(semconv.ServiceNameKey.String("IB.YasDataReader/restapi")))
+	res, err := resource.New(ctx, resource.WithAttributes(semconv.ServiceNameKey.String(serviceName)))
 	if err != nil {
 		log.Error().Err(err).Msg("Unable to create resource")
 		return Telemetry{}, nil
@@ -73,7 +76,7 @@ func Setup(config abstract.Config) (Telemetry, error) {
 		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(metricExporter)),
 	)
 	global.SetMeterProvider(meterProvider)
-	meter := meterProvider.Meter("IB.YasDataApi/restapi")
+	meter := meterProvider.Meter(serviceName)
 	gauge, err := meter.AsyncInt64().
 		Gauge("wc_reader_uptime_gauge", instrument.WithUnit(unit.Milliseconds))
 	if err != nil {
@@ -87,8 +90,10 @@ func Setup(config abstract.Config) (Telemetry, error) {
 		trace.WithResource(res),
 		trace.WithSpanProcessor(bsp),
 	)
+	otel.SetTracerProvider(tracerProvider)
+	otel.SetTextMapPropagator(Propagator)
 
-	_telemetry := Telemetry{ MeterProvider: *meterProvider, Meter: meter, TraceProvider: *tracerProvider, Ctx: ctx, uptimeGauge: gauge }
+	_telemetry := Telemetry{ MeterProvider: meterProvider, Meter: meter, TraceProvider: tracerProvider, Ctx: ctx, uptimeGauge: gauge }
 	_telemetry.SetUptimeGauge(time.Now().UnixMilli())
 	return _telemetry, nil
 }
@@ -101,6 +106,9 @@ func (telemetry *Telemetry) SetUptimeGauge(milTime int64) {
 
 func (telemetry *Telemetry) Shutdown() {
 	log.Debug().Msg("Shutdown telemetry")
+	if telemetry.MeterProvider == nil || telemetry.TraceProvider == nil {
+		return
+	}
 	telemetry.SetUptimeGauge(0)
 	if err := telemetry.MeterProvider.Shutdown(telemetry.Ctx); err != nil {
 		log.Error().Err(err).Msg("Unable to shutdown metrics")
@@ -0,0 +1,249 @@
+package wmm
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Degree and order of the World Magnetic Model
+//
+const MaxDegree = 12
+
+// The model is valid for five years from its epoch
+//
+const validYears = 5.0
+
+// WGS 84 ellipsoid and the geomagnetic reference radius, km
+//
+const (
+	ellipsoidA = 6378.137
+	ellipsoidF = 1 / 298.257223563
+	referenceRadius = 6371.2
+)
+
+var ErrOutOfRange = errors.New("wmm: date is outside of the model validity")
+
+// Coefficients of the model in the NOAA .COF format, shipped with the service so the
+// variation is computed offline. To update the model replace the file with the new release
+//
+//go:embed WMM.COF
+var defaultCoefficients []byte
+
+// Spherical harmonic model of the main field: Gauss coefficients g, h in nT at the epoch
+// and their secular variation gDot, hDot in nT per year
+//
+type Model struct {
+	Name string
+	Epoch float64
+	g, h, gDot, hDot [MaxDegree + 1][MaxDegree + 1]float64
+}
+
+// Magnetic field at the point: north, east, down and horizontal components and the
+// total intensity in nT, declination (variation, east positive) and inclination in degrees
+//
+type Field struct {
+	X, Y, Z float64
+	H, F float64
+	Declination float64
+	Inclination float64
+}
+
+var defaultModel = mustParse(defaultCoefficients)
+
+// Returns the embedded model
+//
+func Default() *Model {
+	return defaultModel
+}
+
+// Magnetic variation of the embedded model at the sea level, degrees east positive
+//
+func Declination(lat float64, lon float64, date time.Time) (float64, error) {
+	field, err := defaultModel.Field(lat, lon, 0, DecimalYear(date))
+	return field.Declination, err
+}
+
+// Year with the fraction of the days passed, e.g. 2022.5 is July 2 2022
+//
+func DecimalYear(date time.Time) float64 {
+	date = date.UTC()
+	start := time.Date(date.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(1, 0, 0)
+	return float64(date.Year()) + float64(date.Sub(start)) / float64(end.Sub(start))
+}
+
+// Parses the model from the NOAA .COF file: the header with the epoch and the model name
+// followed by "n m g h gDot hDot" lines, the lines of 9 end the file
+//
+func Parse(r io.Reader) (*Model, error) {
+	scanner := bufio.NewScanner(r)
+	if !scanner.Scan() {
+		return nil, errors.New("wmm: missing header")
+	}
+	header := strings.Fields(scanner.Text())
+	if len(header) < 2 {
+		return nil, fmt.Errorf("wmm: wrong header %q", scanner.Text())
+	}
+	epoch, err := strconv.ParseFloat(header[0], 64)
+	if err != nil {
+		return nil, fmt.Errorf("wmm: wrong epoch %q", header[0])
+	}
+
+	model := &Model{ Name: header[1], Epoch: epoch }
+	count := 0
+	for line := 2; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if strings.HasPrefix(fields[0], "9999") {
+			break
+		}
+		if len(fields) != 6 {
+			return nil, fmt.Errorf("wmm: line %d: expected 6 fields", line)
+		}
+		n, errN := strconv.Atoi(fields[0])
+		m, errM := strconv.Atoi(fields[1])
+		if errN != nil || errM != nil || n < 1 || n > MaxDegree || m < 0 || m > n {
+			return nil, fmt.Errorf("wmm: line %d: wrong degree and order", line)
+		}
+		var values [4]float64
+		for i := range values {
+			if values[i], err = strconv.ParseFloat(fields[i + 2], 64); err != nil {
+				return nil, fmt.Errorf("wmm: line %d: %w", line, err)
+			}
+		}
+		model.g[n][m], model.h[n][m], model.gDot[n][m], model.hDot[n][m] = values[0], values[1], values[2], values[3]
+		count++
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if expected := (MaxDegree + 1) * (MaxDegree + 2) / 2 - 1; count != expected {
+		return nil, fmt.Errorf("wmm: %d coefficients, expected %d", count, expected)
+	}
+	return model, nil
+}
+
+func mustParse(data []byte) *Model {
+	model, err := Parse(bytes.NewReader(data))
+	if err != nil {
+		panic(err)
+	}
+	return model
+}
+
+// Years of the model validity, from the epoch inclusive to the end exclusive
+//
+func (model *Model) Validity() (float64, float64) {
+	return model.Epoch, model.Epoch + validYears
+}
+
+// Computes the field at the geodetic latitude and longitude in degrees, the height above
+// the WGS 84 ellipsoid in km and the decimal year. Returns ErrOutOfRange outside of the validity
+//
+func (model *Model) Field(lat float64, lon float64, heightKm float64, year float64) (Field, error) {
+	if from, to := model.Validity(); year < from || year >= to {
+		return Field{}, fmt.Errorf("%w %s %.1f - %.1f", ErrOutOfRange, model.Name, from, to)
+	}
+
+	// Declination is undefined at the geographic poles, the nearest point is taken
+	//
+	lat = math.Max(-89.9999, math.Min(89.9999, lat))
+
+	// Geodetic to geocentric spherical coordinates
+	//
+	latRad, lonRad := lat * math.Pi / 180, lon * math.Pi / 180
+	e2 := ellipsoidF * (2 - ellipsoidF)
+	rc := ellipsoidA / math.Sqrt(1 - e2 * math.Sin(latRad) * math.Sin(latRad))
+	p := (rc + heightKm) * math.Cos(latRad)
+	z := (rc * (1 - e2) + heightKm) * math.Sin(latRad)
+	r := math.Hypot(p, z)
+	latSph := math.Asin(z / r)
+
+	legendre, dLegendre := schmidtLegendre(math.Sin(latSph), math.Cos(latSph))
+	dt := year - model.Epoch
+	var xSph, ySph, zSph float64
+	for n := 1; n <= MaxDegree; n++ {
+		ratio := math.Pow(referenceRadius / r, float64(n + 2))
+		for m := 0; m <= n; m++ {
+			g := model.g[n][m] + dt * model.gDot[n][m]
+			h := model.h[n][m] + dt * model.hDot[n][m]
+			cosM, sinM := math.Cos(float64(m) * lonRad), math.Sin(float64(m) * lonRad)
+
+			xSph += ratio * (g * cosM + h * sinM) * dLegendre[n][m]
+			ySph += ratio * float64(m) * (g * sinM - h * cosM) * legendre[n][m]
+			zSph -= ratio * float64(n + 1) * (g * cosM + h * sinM) * legendre[n][m]
+		}
+	}
+	ySph /= math.Cos(latSph)
+
+	// Rotate the spherical components to the ellipsoid
+	//
+	psi := latSph - latRad
+	var field Field
+	field.X = xSph * math.Cos(psi) - zSph * math.Sin(psi)
+	field.Y = ySph
+	field.Z = xSph * math.Sin(psi) + zSph * math.Cos(psi)
+	field.H = math.Hypot(field.X, field.Y)
+	field.F = math.Hypot(field.H, field.Z)
+	field.Declination = math.Atan2(field.Y, field.X) * 180 / math.Pi
+	field.Inclination = math.Atan2(field.Z, field.H) * 180 / math.Pi
+	return field, nil
+}
+
+// Schmidt semi-normalized associated Legendre functions of cos(colatitude) and their
+// derivatives by colatitude, x is sin(latitude) and s is cos(latitude)
+//
+func schmidtLegendre(x float64, s float64) ([MaxDegree + 1][MaxDegree + 1]float64, [MaxDegree + 1][MaxDegree + 1]float64) {
+	var p, dp [MaxDegree + 1][MaxDegree + 1]float64
+	p[0][0] = 1
+	for n := 1; n <= MaxDegree; n++ {
+		for m := 0; m <= n; m++ {
+			switch {
+				case n == m:
+					p[n][m] = s * p[n - 1][m - 1]
+					dp[n][m] = s * dp[n - 1][m - 1] + x * p[n - 1][m - 1]
+				case n == 1:
+					p[n][m] = x * p[n - 1][m]
+					dp[n][m] = x * dp[n - 1][m] - s * p[n - 1][m]
+				default:
+					k := float64((n - 1) * (n - 1) - m * m) / float64((2 * n - 1) * (2 * n - 3))
+					var p2, dp2 float64
+					if m <= n - 2 {
+						p2, dp2 = p[n - 2][m], dp[n - 2][m]
+					}
+					p[n][m] = x * p[n - 1][m] - k * p2
+					dp[n][m] = x * dp[n - 1][m] - s * p[n - 1][m] - k * dp2
+			}
+		}
+	}
+
+	// Gauss normalized functions above are scaled to the Schmidt normalization
+	//
+	schmidt := 1.0
+	for n := 1; n <= MaxDegree; n++ {
+		schmidt *= float64(2 * n - 1) / float64(n)
+		factor := schmidt
+		for m := 0; m <= n; m++ {
+			if m > 0 {
+				scale := float64(n - m + 1) / float64(n + m)
+				if m == 1 {
+					scale *= 2
+				}
+				factor *= math.Sqrt(scale)
+			}
+			p[n][m] *= factor
+			dp[n][m] *= factor
+		}
+	}
+	return p, dp
+}
@@ -0,0 +1,108 @@
+package wmm
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+// WMM2025 field at the test points of the technical report, components in nT, angles in degrees
+//
+var wmm2025TestValues = []struct {
+	year, height, lat, lon float64
+	x, y, z, h, f, i, d float64
+}{
+	{ 2025.0, 0, 80, 0, 6521.6, 145.9, 54791.5, 6523.2, 55178.5, 83.21, 1.28 },
+	{ 2025.0, 0, 0, 120, 39678.0, -109.7, -10580.5, 39678.2, 41064.7, -14.93, -0.16 },
+	{ 2025.0, 0, -80, 240, 6117.6, 15751.9, -52022.5, 16898.2, 54698.2, -72.00, 68.78 },
+	{ 2025.0, 100, 80, 0, 6216.0, 92.5, 52598.8, 6216.7, 52964.9, 83.26, 0.85 },
+	{ 2025.0, 100, 0, 120, 37688.8, -96.3, -10152.4, 37688.9, 39032.4, -15.08, -0.15 },
+	{ 2025.0, 100, -80, 240, 5907.6, 14780.3, -49540.7, 15917.2, 52035.0, -72.19, 68.21 },
+	{ 2027.5, 0, 80, 0, 6500.8, 294.6, 54869.4, 6507.5, 55253.9, 83.24, 2.59 },
+	{ 2027.5, 0, 0, 120, 39701.9, -167.5, -10382.1, 39702.3, 41037.3, -14.65, -0.24 },
+	{ 2027.5, 0, -80, 240, 6200.8, 15730.3, -51783.6, 16908.4, 54474.2, -71.92, 68.49 },
+	{ 2027.5, 100, 80, 0, 6196.7, 233.8, 52670.5, 6201.1, 53034.3, 83.29, 2.16 },
+	{ 2027.5, 100, 0, 120, 37711.8, -148.8, -9970.1, 37712.1, 39007.7, -14.81, -0.23 },
+	{ 2027.5, 100, -80, 240, 5984.0, 14760.2, -49317.7, 15927.0, 51825.7, -72.10, 67.93 },
+}
+
+func TestFieldMatchesTestValues(t *testing.T) {
+	model := Default()
+	if model.Name != "WMM-2025" {
+		t.Fatalf("test values are of WMM-2025, the embedded model is %s", model.Name)
+	}
+
+	for _, test := range wmm2025TestValues {
+		field, err := model.Field(test.lat, test.lon, test.height, test.year)
+		if err != nil {
+			t.Fatalf("%v %v %v %v: %v", test.year, test.height, test.lat, test.lon, err)
+		}
+		checks := []struct {
+			name string
+			got, want, tolerance float64
+		}{
+			{ "X", field.X, test.x, 0.05 },
+			{ "Y", field.Y, test.y, 0.05 },
+			{ "Z", field.Z, test.z, 0.05 },
+			{ "H", field.H, test.h, 0.05 },
+			{ "F", field.F, test.f, 0.05 },
+			{ "I", field.Inclination, test.i, 0.005 },
+			{ "D", field.Declination, test.d, 0.005 },
+		}
+		for _, check := range checks {
+			// values of the report are rounded, so the tolerance is half of the last digit
+			// with a margin for the float rounding
+			if math.Abs(check.got - check.want) > check.tolerance + 1e-9 {
+				t.Errorf("%v %v km %v %v: %s = %.3f, want %.2f", test.year, test.height, test.lat, test.lon, check.name, check.got, check.want)
+			}
+		}
+	}
+}
+
+func TestFieldOutOfRange(t *testing.T) {
+	from, to := Default().Validity()
+	for _, year := range []float64{ from - 0.01, to } {
+		if _, err := Default().Field(0, 0, 0, year); !errors.Is(err, ErrOutOfRange) {
+			t.Errorf("year %v: error = %v, want %v", year, err, ErrOutOfRange)
+		}
+	}
+}
+
+func TestDeclinationAtPole(t *testing.T) {
+	declination, err := Default().Field(90, 0, 0, Default().Epoch)
+	if err != nil || math.IsNaN(declination.Declination) {
+		t.Errorf("declination at the pole = %v, %v", declination.Declination, err)
+	}
+}
+
+func TestDecimalYear(t *testing.T) {
+	tests := []struct {
+		date time.Time
+		want float64
+	}{
+		{ time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), 2020.0 },
+		{ time.Date(2022, 7, 2, 12, 0, 0, 0, time.UTC), 2022.5 },
+		{ time.Date(2024, 7, 2, 0, 0, 0, 0, time.UTC), 2024.5 },
+	}
+	for _, test := range tests {
+		if got := DecimalYear(test.date); math.Abs(got - test.want) > 1e-9 {
+			t.Errorf("DecimalYear(%v) = %v, want %v", test.date, got, test.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := map[string]string {
+		"empty": "",
+		"wrong epoch": "epoch WMM-2020 12/10/2019\n",
+		"missing coefficients": "    2020.0            WMM-2020        12/10/2019\n  1  0  -29404.5       0.0        6.7        0.0\n",
+		"wrong degree": "    2020.0            WMM-2020        12/10/2019\n 13  0  1.0  0.0  0.0  0.0\n",
+	}
+	for name, data := range tests {
+		if _, err := Parse(strings.NewReader(data)); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}